@@ -0,0 +1,235 @@
+package mapper
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var (
+	bigIntType   = reflect.TypeOf(big.Int{})
+	bigFloatType = reflect.TypeOf(big.Float{})
+	bigRatType   = reflect.TypeOf(big.Rat{})
+
+	bigIntTooLarge    = "Provided value `%s` for field `%s` exceeds %d bits"
+	wrongPrecision    = "Unknown precision `%s` for field `%s`"
+	wrongRoundingMode = "Unknown rounding mode `%s` for field `%s`"
+
+	roundingModes = map[string]big.RoundingMode{
+		"even":         big.ToNearestEven,
+		"away":         big.ToNearestAway,
+		"zero":         big.ToZero,
+		"awayfromzero": big.AwayFromZero,
+		"down":         big.ToNegativeInf,
+		"up":           big.ToPositiveInf,
+	}
+)
+
+// setBig decodes value into a big.Int, big.Float or big.Rat. The boolean
+// result is false when v is none of these. Options:
+//
+//	big.Int    base as for other integers, prec=N limits the value to N bits,
+//	           round=MODE accepts decimals and rounds them to an integer
+//	big.Float  prec=N sets the mantissa precision in bits, round=MODE the
+//	           rounding mode, nonfinite accepts Inf
+//	big.Rat    prec=N rounds to N decimal places using round=MODE
+//
+// Rounding modes are even (the default), away, zero, awayfromzero, down and
+// up, after the big.RoundingMode constants.
+func setBig(v reflect.Value, value string, fieldName string, opts TagOptions) (bool, error) {
+	switch v.Type() {
+	case bigIntType, bigFloatType, bigRatType:
+	default:
+		return false, nil
+	}
+
+	prec, err := bigPrecision(fieldName, opts)
+	if err != nil {
+		return true, err
+	}
+	mode, err := roundingMode(fieldName, opts)
+	if err != nil {
+		return true, err
+	}
+
+	switch v.Type() {
+	case bigIntType:
+		base, err := intBase(fieldName, opts)
+		if err != nil {
+			return true, err
+		}
+
+		digits := value
+		if base == 0 {
+			digits = decimalLeadingZeros(value)
+		}
+
+		i, ok := new(big.Int).SetString(digits, base)
+		if !ok && opts.Contains("round") && base == 10 {
+			if r, ratOk := new(big.Rat).SetString(value); ratOk && !strings.Contains(value, "/") {
+				i, ok = roundRat(r, mode), true
+			}
+		}
+		if !ok {
+			return true, errors.New(fmt.Sprintf(wrongIntType, value, fieldName))
+		}
+		if prec > 0 && uint(i.BitLen()) > prec {
+			return true, errors.New(fmt.Sprintf(bigIntTooLarge, value, fieldName, prec))
+		}
+		v.Addr().Interface().(*big.Int).Set(i)
+	case bigFloatType:
+		// Without a precision, SetString uses 64 bits.
+		f := new(big.Float).SetPrec(prec).SetMode(mode)
+		if _, ok := f.SetString(value); !ok {
+			return true, errors.New(fmt.Sprintf(wrongFloatType, value, fieldName))
+		}
+		if f.IsInf() && !opts.Contains("nonfinite") {
+			return true, errors.New(fmt.Sprintf(nonFiniteFloat, value, fieldName))
+		}
+		v.Addr().Interface().(*big.Float).Copy(f)
+	case bigRatType:
+		r, ok := new(big.Rat).SetString(value)
+		if !ok {
+			return true, errors.New(fmt.Sprintf(wrongFloatType, value, fieldName))
+		}
+		if opts.Contains("prec") {
+			scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(prec)), nil)
+			scaled := new(big.Rat).Mul(r, new(big.Rat).SetInt(scale))
+			r.SetFrac(roundRat(scaled, mode), scale)
+		}
+		v.Addr().Interface().(*big.Rat).Set(r)
+	}
+
+	return true, nil
+}
+
+func bigPrecision(fieldName string, opts TagOptions) (uint, error) {
+	if !opts.Contains("prec") {
+		return 0, nil
+	}
+
+	prec, err := strconv.ParseUint(opts["prec"], 10, 32)
+	if err != nil {
+		return 0, errors.New(fmt.Sprintf(wrongPrecision, opts["prec"], fieldName))
+	}
+	return uint(prec), nil
+}
+
+func roundingMode(fieldName string, opts TagOptions) (big.RoundingMode, error) {
+	if opts["round"] == "" {
+		return big.ToNearestEven, nil
+	}
+
+	mode, ok := roundingModes[strings.ToLower(opts["round"])]
+	if !ok {
+		return 0, errors.New(fmt.Sprintf(wrongRoundingMode, opts["round"], fieldName))
+	}
+	return mode, nil
+}
+
+// roundRat rounds r to an integer using mode.
+func roundRat(r *big.Rat, mode big.RoundingMode) *big.Int {
+	q, m := new(big.Int).QuoRem(r.Num(), r.Denom(), new(big.Int))
+	if m.Sign() == 0 {
+		return q
+	}
+
+	// q is truncated towards zero; decide whether to move one step away.
+	negative := r.Sign() < 0
+	twice := new(big.Int).Abs(m)
+	twice.Lsh(twice, 1)
+	half := twice.Cmp(r.Denom()) // compares |remainder| against one half
+
+	var away bool
+	switch mode {
+	case big.ToZero:
+		away = false
+	case big.AwayFromZero:
+		away = true
+	case big.ToNegativeInf:
+		away = negative
+	case big.ToPositiveInf:
+		away = !negative
+	case big.ToNearestAway:
+		away = half >= 0
+	default: // big.ToNearestEven
+		away = half > 0 || (half == 0 && q.Bit(0) == 1)
+	}
+
+	if away {
+		if negative {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	return q
+}
+
+// formatBig is the reverse of setBig. Values are never written in scientific
+// notation. The boolean result is false when v is not a big number.
+func formatBig(v reflect.Value, fieldName string, opts TagOptions) (string, bool, error) {
+	switch v.Type() {
+	case bigIntType:
+		base, err := intBase(fieldName, opts)
+		if err != nil {
+			return "", false, err
+		}
+		if base == 0 {
+			base = 10
+		}
+		i := addressable(v).Addr().Interface().(*big.Int)
+		return i.Text(base), true, nil
+	case bigFloatType:
+		f := addressable(v).Addr().Interface().(*big.Float)
+		return f.Text('f', -1), true, nil
+	case bigRatType:
+		r := addressable(v).Addr().Interface().(*big.Rat)
+		if opts.Contains("prec") {
+			prec, err := bigPrecision(fieldName, opts)
+			if err != nil {
+				return "", false, err
+			}
+			return r.FloatString(int(prec)), true, nil
+		}
+		if digits, ok := decimalDigits(r.Denom()); ok {
+			return r.FloatString(digits), true, nil
+		}
+		return r.RatString(), true, nil
+	}
+
+	return "", false, nil
+}
+
+// decimalDigits returns the number of decimal places needed to write a
+// fraction with the given denominator exactly, if that is possible at all.
+func decimalDigits(denom *big.Int) (int, bool) {
+	d := new(big.Int).Set(denom)
+	m := new(big.Int)
+
+	var twos, fives int
+	for d.Bit(0) == 0 && d.Sign() > 0 {
+		d.Rsh(d, 1)
+		twos++
+	}
+	five := big.NewInt(5)
+	for {
+		q, r := new(big.Int).QuoRem(d, five, m)
+		if r.Sign() != 0 {
+			break
+		}
+		d = q
+		fives++
+	}
+
+	if d.Cmp(big.NewInt(1)) != 0 {
+		return 0, false
+	}
+	if twos > fives {
+		return twos, true
+	}
+	return fives, true
+}
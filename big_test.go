@@ -0,0 +1,85 @@
+package mapper_test
+
+import (
+	"github.com/assertis/url-mapper"
+	"github.com/stretchr/testify/assert"
+	"math/big"
+	"net/url"
+	"testing"
+)
+
+type TestBigRequest struct {
+	Reference *big.Int   `query:"ref"`
+	Hex       *big.Int   `query:"hex,base=16"`
+	Rounded   *big.Int   `query:"rounded,round=away"`
+	Amount    *big.Float `query:"amount,prec=200"`
+	Ratio     *big.Rat   `query:"ratio"`
+	Share     *big.Rat   `query:"share,prec=2,round=down"`
+	Limited   *big.Int   `query:"limited,prec=8"`
+}
+
+func TestMappingBigNumbers(t *testing.T) {
+	var r = TestBigRequest{}
+
+	values, err := url.ParseQuery("ref=123456789012345678901234567890&hex=ffffffffffffffffffff&rounded=2.5&amount=12345678901234567890.123456789&ratio=1/3&share=0.129")
+	assert.Nil(t, err)
+
+	err = mapper.Unmarshal(values, &r)
+	assert.Nil(t, err)
+
+	ref, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	assert.Equal(t, 0, ref.Cmp(r.Reference))
+	hex, _ := new(big.Int).SetString("ffffffffffffffffffff", 16)
+	assert.Equal(t, 0, hex.Cmp(r.Hex))
+	assert.Equal(t, int64(3), r.Rounded.Int64())
+	assert.Equal(t, uint(200), r.Amount.Prec())
+	assert.Equal(t, "12345678901234567890.123456789", r.Amount.Text('f', 9))
+	assert.Equal(t, "1/3", r.Ratio.RatString())
+	assert.Equal(t, "3/25", r.Share.RatString())
+	assert.Nil(t, r.Limited)
+
+	out, err := mapper.Marshal(r)
+	assert.Nil(t, err)
+	assert.Equal(t, "123456789012345678901234567890", out.Get("ref"))
+	assert.Equal(t, "ffffffffffffffffffff", out.Get("hex"))
+	assert.Equal(t, "1/3", out.Get("ratio"))
+	assert.Equal(t, "0.12", out.Get("share"))
+	assert.NotContains(t, out.Get("amount"), "e")
+}
+
+func TestMarshalBigNumbersWithoutExponent(t *testing.T) {
+	r := struct {
+		Float *big.Float `query:"f"`
+		Rat   *big.Rat   `query:"r"`
+	}{new(big.Float).SetFloat64(1e21), big.NewRat(1, 8)}
+
+	values, err := mapper.Marshal(r)
+	assert.Nil(t, err)
+	assert.Equal(t, "1000000000000000000000", values.Get("f"))
+	assert.Equal(t, "0.125", values.Get("r"))
+}
+
+func TestIncorrectBigNumbers(t *testing.T) {
+	var r = TestBigRequest{}
+
+	errors := map[string]string{
+		"ref=12.5":    "Provided value `12.5` for field `Reference` is not an integer",
+		"amount=lots": "Provided value `lots` for field `Amount` is not a number",
+		"amount=Inf":  "Provided value `Inf` for field `Amount` is not a finite number",
+		"ratio=1/0":   "Provided value `1/0` for field `Ratio` is not a number",
+		"limited=256": "Provided value `256` for field `Limited` exceeds 8 bits",
+	}
+	for query, expected := range errors {
+		values, err := url.ParseQuery(query)
+		assert.Nil(t, err)
+
+		err = mapper.Unmarshal(values, &r)
+		assert.EqualError(t, err, expected, query)
+	}
+
+	var bad struct {
+		Amount *big.Float `query:"amount,round=sideways"`
+	}
+	err := mapper.Unmarshal(url.Values{"amount": {"1"}}, &bad)
+	assert.EqualError(t, err, "Unknown rounding mode `sideways` for field `Amount`")
+}
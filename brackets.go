@@ -0,0 +1,104 @@
+package mapper
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+var (
+	bracketDepthExceeded = "Key `%s` exceeds the maximum nesting depth of %d"
+	bracketIndexExceeded = "Key `%s` exceeds the maximum index of %d"
+	bracketAppendNested  = "Key `%s` uses `[]` before the last segment"
+)
+
+// expandBrackets makes PHP/Rails style keys such as `passengers[0][age]`
+// available under their separator-joined form `passengers.0.age`, so they
+// are decoded like any other nested key. A trailing `[]` appends to the plain
+// key, `ids[]=1&ids[]=2` being read as `ids=1&ids=2`. The original keys are
+// kept as they were.
+func (d *Decoder) expandBrackets(values url.Values) (url.Values, error) {
+	var expanded url.Values
+	for key := range values {
+		if strings.Contains(key, "[") {
+			expanded = make(url.Values, len(values))
+			break
+		}
+	}
+	if expanded == nil {
+		return values, nil
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		vals := values[key]
+		expanded[key] = append(expanded[key], vals...)
+
+		base, segments, ok := parseBracketKey(key)
+		if !ok {
+			continue
+		}
+
+		if len(segments) > d.maxDepth() {
+			return nil, errors.New(fmt.Sprintf(bracketDepthExceeded, key, d.maxDepth()))
+		}
+
+		for i, segment := range segments {
+			if segment == "" {
+				if i != len(segments)-1 {
+					return nil, errors.New(fmt.Sprintf(bracketAppendNested, key))
+				}
+				segments = segments[:i]
+				break
+			}
+		}
+
+		joined := base
+		for _, segment := range segments {
+			joined = d.key(joined, segment)
+		}
+		expanded[joined] = append(expanded[joined], vals...)
+	}
+
+	return expanded, nil
+}
+
+// parseBracketKey splits `a[b][c]` into `a` and the segments `b` and `c`.
+// Keys that are not made of a name followed by bracketed segments are
+// reported as not ok.
+func parseBracketKey(key string) (string, []string, bool) {
+	open := strings.Index(key, "[")
+	if open <= 0 {
+		return "", nil, false
+	}
+
+	base, rest := key[:open], key[open:]
+	var segments []string
+	for rest != "" {
+		if rest[0] != '[' {
+			return "", nil, false
+		}
+
+		end := strings.Index(rest, "]")
+		if end < 0 {
+			return "", nil, false
+		}
+
+		segment := rest[1:end]
+		if strings.Contains(segment, "[") {
+			return "", nil, false
+		}
+
+		segments = append(segments, segment)
+		rest = rest[end+1:]
+	}
+
+	return base, segments, true
+}
@@ -0,0 +1,125 @@
+package mapper_test
+
+import (
+	"github.com/assertis/url-mapper"
+	"github.com/stretchr/testify/assert"
+	"net/url"
+	"testing"
+)
+
+type TestPassenger struct {
+	Age      int    `query:"age"`
+	Railcard string `query:"railcard"`
+}
+
+type TestFilter struct {
+	Operator string `query:"operator"`
+}
+
+type TestBracketRequest struct {
+	Passengers []TestPassenger `query:"passengers"`
+	Filter     TestFilter      `query:"filter"`
+	IDs        []int           `query:"ids"`
+	Codes      [2]string       `query:"codes"`
+}
+
+func TestMappingBrackets(t *testing.T) {
+	var r = TestBracketRequest{}
+
+	values, err := url.ParseQuery("passengers[0][age]=30&passengers[0][railcard]=YNG&passengers[1][age]=4&filter[operator]=SW&ids[]=3&ids[]=5")
+	assert.Nil(t, err)
+
+	err = mapper.Unmarshal(values, &r)
+	assert.Nil(t, err)
+
+	assert.Equal(t, []TestPassenger{{30, "YNG"}, {4, ""}}, r.Passengers)
+	assert.Equal(t, "SW", r.Filter.Operator)
+	assert.Equal(t, []int{3, 5}, r.IDs)
+
+	// As written by PHP's http_build_query.
+	values, err = url.ParseQuery("ids[1]=5&ids[0]=3&codes[0]=TBW&codes[1]=LBG")
+	assert.Nil(t, err)
+
+	err = mapper.Unmarshal(values, &r)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{3, 5}, r.IDs)
+	assert.Equal(t, [2]string{"TBW", "LBG"}, r.Codes)
+
+	err = mapper.Unmarshal(url.Values{"ids[5000]": {"1"}}, &r)
+	assert.EqualError(t, err, "Key `ids.5000` exceeds the maximum index of 1000")
+}
+
+func TestMappingIndexedKeys(t *testing.T) {
+	var r = TestBracketRequest{}
+
+	values, err := url.ParseQuery("passengers.7.age=30&passengers.2.age=4")
+	assert.Nil(t, err)
+
+	err = mapper.Unmarshal(values, &r)
+	assert.Nil(t, err)
+
+	assert.Equal(t, []TestPassenger{{4, ""}, {30, ""}}, r.Passengers)
+
+	// Non-canonical indices and indices without keys below them are ignored.
+	values, err = url.ParseQuery("passengers[01][age]=5&passengers.1.age=6&passengers[2]=1&ids.01=3&ids.1=4")
+	assert.Nil(t, err)
+
+	r = TestBracketRequest{}
+	err = mapper.Unmarshal(values, &r)
+	assert.Nil(t, err)
+
+	assert.Equal(t, []TestPassenger{{6, ""}}, r.Passengers)
+	assert.Equal(t, []int{4}, r.IDs)
+}
+
+func TestMappingBracketsErrors(t *testing.T) {
+	var r = TestBracketRequest{}
+
+	values, err := url.ParseQuery("passengers[0][age]=X")
+	assert.Nil(t, err)
+
+	err = mapper.Unmarshal(values, &r)
+	assert.EqualError(t, err, "Provided value `X` for field `Passengers[0].Age` is not an integer")
+
+	values, err = url.ParseQuery("passengers[5000][age]=30")
+	assert.Nil(t, err)
+
+	err = mapper.Unmarshal(values, &r)
+	assert.EqualError(t, err, "Key `passengers.5000.age` exceeds the maximum index of 1000")
+
+	decoder := mapper.Decoder{MaxIndex: 3}
+	values, err = url.ParseQuery("passengers.4.age=30")
+	assert.Nil(t, err)
+
+	err = decoder.Unmarshal(values, &r)
+	assert.EqualError(t, err, "Key `passengers.4.age` exceeds the maximum index of 3")
+
+	decoder = mapper.Decoder{MaxDepth: 2}
+	values, err = url.ParseQuery("a[b][c][d]=1")
+	assert.Nil(t, err)
+
+	err = decoder.Unmarshal(values, &r)
+	assert.EqualError(t, err, "Key `a[b][c][d]` exceeds the maximum nesting depth of 2")
+
+	values, err = url.ParseQuery("passengers[][age]=1")
+	assert.Nil(t, err)
+
+	err = mapper.Unmarshal(values, &r)
+	assert.EqualError(t, err, "Key `passengers[][age]` uses `[]` before the last segment")
+}
+
+func TestMappingBracketsMapKeys(t *testing.T) {
+	var r struct {
+		Seen map[int]bool      `query:"id"`
+		Meta map[string]string `query:"meta"`
+	}
+
+	// Only slice indices are limited by MaxIndex.
+	values, err := url.ParseQuery("id[5000]=1&meta[2024]=x&utm[99999]=y")
+	assert.Nil(t, err)
+
+	err = mapper.Unmarshal(values, &r)
+	assert.Nil(t, err)
+	assert.Equal(t, map[int]bool{5000: true}, r.Seen)
+	assert.Equal(t, map[string]string{"2024": "x"}, r.Meta)
+}
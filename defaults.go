@@ -0,0 +1,222 @@
+package mapper
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	errWrongPrepareType = errors.New("Prepare only works with pointers")
+	invalidConfig       = "Invalid configuration of field `%s` in %s: %s"
+	unsupportedDefault  = "Option `default` is not supported for field `%s`"
+)
+
+// ConfigError reports a struct tag that cannot be used as written, such as
+// a default value that does not convert to the field's type. Unlike other
+// errors it does not depend on the query being decoded.
+type ConfigError struct {
+	// Type is the struct holding the field.
+	Type reflect.Type
+	// Field is the Go path of the field within Type.
+	Field string
+	Err   error
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf(invalidConfig, e.Field, e.Type, e.Err)
+}
+
+// Prepare parses the default values of the struct pointed to by v and of
+// the structs it contains, using the default Decoder, and checks that no
+// pattern was cut at a comma. Unmarshal does the same the first time it
+// meets a type, and fails for as long as one of them is invalid; calling
+// Prepare at startup reports a bad tag before the first request. Other tag
+// options are only checked while decoding.
+func Prepare(v interface{}) error {
+	return defaultDecoder.Prepare(v)
+}
+
+// Prepare is like the package function Prepare, for this Decoder.
+func (d *Decoder) Prepare(v interface{}) error {
+	t := reflect.TypeOf(v)
+	if t == nil || t.Kind() != reflect.Ptr {
+		return errWrongPrepareType
+	}
+	return d.prepared(t.Elem())
+}
+
+type prepareKey struct {
+	typ reflect.Type
+	loc string // see locationKey
+}
+
+var prepareCache sync.Map // map[prepareKey]preparedType
+
+type preparedType struct {
+	err error
+}
+
+// locationKey identifies the Decoder's Location in the caches by its name,
+// so that the caches do not grow with every *time.Location created.
+func (d *Decoder) locationKey() string {
+	if d.Location == nil {
+		return ""
+	}
+	return d.Location.String()
+}
+
+// prepared is like prepare but uses a cache, so that the defaults of a type
+// are only parsed once.
+func (d *Decoder) prepared(t reflect.Type) error {
+	key := prepareKey{t, d.locationKey()}
+	if p, ok := prepareCache.Load(key); ok {
+		return p.(preparedType).err
+	}
+
+	p, _ := prepareCache.LoadOrStore(key, preparedType{d.prepare(t, map[reflect.Type]bool{})})
+	return p.(preparedType).err
+}
+
+func (d *Decoder) prepare(t reflect.Type, seen map[reflect.Type]bool) error {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map {
+		t = t.Elem()
+	}
+	if !isStructType(t) || seen[t] {
+		return nil
+	}
+	seen[t] = true
+
+	for _, f := range cachedTypeFields(t) {
+		if f.opts.Contains("pattern") {
+			if err := checkPattern(f.tag, f.path); err != nil {
+				return &ConfigError{Type: t, Field: f.path, Err: err}
+			}
+		}
+		if f.opts.Contains("default") {
+			if _, err := d.defaultValue(t, f); err != nil {
+				return err
+			}
+		}
+		if err := d.prepare(f.typ, seen); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type defaultKey struct {
+	typ  reflect.Type // struct holding the field
+	path string
+	loc  string // see locationKey
+}
+
+type parsedDefault struct {
+	value reflect.Value
+	err   error
+}
+
+var defaultCache sync.Map // map[defaultKey]parsedDefault
+
+// defaultValue returns the value given in the "default" option of the field
+// f of the struct type t, parsed like a query value the first time it is
+// needed. Slices take several values separated by `|`. The value `now` is
+// returned invalid, as it is only known when decoding.
+func (d *Decoder) defaultValue(t reflect.Type, f field) (reflect.Value, error) {
+	key := defaultKey{t, f.path, d.locationKey()}
+	if p, ok := defaultCache.Load(key); ok {
+		return p.(parsedDefault).value, p.(parsedDefault).err
+	}
+
+	value, err := d.parseDefault(f)
+	if err != nil {
+		err = &ConfigError{Type: t, Field: f.path, Err: err}
+	}
+
+	p, _ := defaultCache.LoadOrStore(key, parsedDefault{value, err})
+	return p.(parsedDefault).value, p.(parsedDefault).err
+}
+
+func (d *Decoder) parseDefault(f field) (reflect.Value, error) {
+	t := f.typ
+	for t.Kind() == reflect.Ptr && !isRegisteredType(t) {
+		t = t.Elem()
+	}
+
+	if isNestedType(f.typ) {
+		return reflect.Value{}, errors.New(fmt.Sprintf(unsupportedDefault, f.path))
+	}
+
+	if f.opts["default"] == "now" {
+		if t != timeType {
+			return reflect.Value{}, errors.New(fmt.Sprintf(unsupportedDefault, f.path))
+		}
+		_, err := d.location(f.path, f.opts)
+		return reflect.Value{}, err
+	}
+
+	defaults := []string{f.opts["default"]}
+	if (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) && !isCustomType(t) {
+		defaults = strings.Split(f.opts["default"], "|")
+	}
+
+	v := reflect.New(f.typ).Elem()
+	if err := d.decodeField(url.Values{"default": defaults}, v, "default", f.path, f.opts, 0); err != nil {
+		return reflect.Value{}, err
+	}
+	return v, nil
+}
+
+// setDefault sets the field f of the struct type t, held in v, to its
+// default value.
+func (d *Decoder) setDefault(t reflect.Type, f field, v reflect.Value, fieldName string) error {
+	value, err := d.defaultValue(t, f)
+	if err != nil {
+		return err
+	}
+
+	if !value.IsValid() { // default=now
+		loc, _ := d.location(fieldName, f.opts)
+		now := time.Now()
+		if loc != nil {
+			now = now.In(loc)
+		}
+		if f.opts.Contains("utc") {
+			now = now.UTC()
+		}
+		indirect(v).Set(reflect.ValueOf(now))
+		return nil
+	}
+
+	copyValue(v, value)
+	return nil
+}
+
+// copyValue sets dst to a copy of src that shares no memory with it, so that
+// cached defaults cannot be changed through the structs they are copied to.
+func copyValue(dst, src reflect.Value) {
+	switch {
+	case src.Type() == bigIntType || src.Type() == bigFloatType || src.Type() == bigRatType:
+		dst.Addr().MethodByName("Set").Call([]reflect.Value{src.Addr()})
+	case src.Kind() == reflect.Ptr && !src.IsNil():
+		p := reflect.New(src.Type().Elem())
+		copyValue(p.Elem(), src.Elem())
+		dst.Set(p)
+	case src.Kind() == reflect.Slice && !src.IsNil():
+		s := reflect.MakeSlice(src.Type(), src.Len(), src.Len())
+		for i := 0; i < src.Len(); i++ {
+			copyValue(s.Index(i), src.Index(i))
+		}
+		dst.Set(s)
+	case src.Kind() == reflect.Array:
+		for i := 0; i < src.Len(); i++ {
+			copyValue(dst.Index(i), src.Index(i))
+		}
+	default:
+		dst.Set(src)
+	}
+}
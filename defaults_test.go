@@ -0,0 +1,115 @@
+package mapper_test
+
+import (
+	"github.com/assertis/url-mapper"
+	"github.com/stretchr/testify/assert"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type TestDefaultsRequest struct {
+	Adults   int            `query:"adults,default=1,min=1"`
+	Children *int           `query:"children,default=0"`
+	Classes  []string       `query:"classes,default=std|first"`
+	Window   time.Duration  `query:"window,default=2h"`
+	Fare     int64          `query:"fare,money,default=9.99"`
+	Outward  time.Time      `query:"outward,layout=dateonly,default=now,utc"`
+	Inward   time.Time      `query:"inward,layout=dateonly,default=2017-01-12"`
+	Ref      TestBookingRef `query:"ref,default=ABC-1"`
+	Railcard string         `query:"railcard"`
+	Pax      int            `query:"pax,default=0,required"`
+}
+
+func TestMappingDefaults(t *testing.T) {
+	var r = TestDefaultsRequest{}
+
+	before := time.Now()
+	err := mapper.Unmarshal(url.Values{"railcard": {"YNG"}}, &r)
+	assert.Nil(t, err)
+
+	assert.Equal(t, 1, r.Adults)
+	assert.Equal(t, 0, *r.Children)
+	assert.Equal(t, []string{"std", "first"}, r.Classes)
+	assert.Equal(t, 2*time.Hour, r.Window)
+	assert.Equal(t, int64(999), r.Fare)
+	assert.False(t, r.Outward.Before(before.Truncate(time.Second)))
+	assert.Equal(t, time.UTC, r.Outward.Location())
+	assert.Equal(t, time.Date(2017, 1, 12, 0, 0, 0, 0, time.UTC), r.Inward)
+	assert.Equal(t, TestBookingRef{"ABC", 1}, r.Ref)
+	assert.Equal(t, 0, r.Pax)
+	assert.Equal(t, "YNG", r.Railcard)
+
+	values, err := url.ParseQuery("adults=2&children=1&classes=first&window=30m")
+	assert.Nil(t, err)
+
+	err = mapper.Unmarshal(values, &r)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, r.Adults)
+	assert.Equal(t, 1, *r.Children)
+	assert.Equal(t, []string{"first"}, r.Classes)
+	assert.Equal(t, 30*time.Minute, r.Window)
+}
+
+func TestMappingDefaultsAreCopied(t *testing.T) {
+	var first, second TestDefaultsRequest
+
+	assert.Nil(t, mapper.Unmarshal(url.Values{}, &first))
+	first.Classes[0] = "changed"
+	*first.Children = 5
+
+	assert.Nil(t, mapper.Unmarshal(url.Values{}, &second))
+	assert.Equal(t, []string{"std", "first"}, second.Classes)
+	assert.Equal(t, 0, *second.Children)
+}
+
+func TestIncorrectDefaults(t *testing.T) {
+	var badInt struct {
+		Adults int `query:"adults,default=one"`
+	}
+
+	err := mapper.Unmarshal(url.Values{}, &badInt)
+	assert.EqualError(t, err, "Invalid configuration of field `Adults` in struct { Adults int \"query:\\\"adults,default=one\\\"\" }: "+
+		"Provided value `one` for field `Adults` is not an integer")
+
+	configErr, ok := err.(*mapper.ConfigError)
+	assert.True(t, ok)
+	assert.Equal(t, "Adults", configErr.Field)
+	assert.Equal(t, reflect.TypeOf(badInt), configErr.Type)
+
+	// A misconfigured type fails whether the key is given or not.
+	err = mapper.Unmarshal(url.Values{"adults": {"2"}}, &badInt)
+	assert.IsType(t, &mapper.ConfigError{}, err)
+
+	var badNested struct {
+		Legs []TestPreparedLeg `query:"legs"`
+	}
+	err = mapper.Unmarshal(url.Values{}, &badNested)
+	assert.IsType(t, &mapper.ConfigError{}, err)
+
+	var badNow struct {
+		Adults int `query:"adults,default=now"`
+	}
+	err = mapper.Unmarshal(url.Values{}, &badNow)
+	assert.IsType(t, &mapper.ConfigError{}, err)
+}
+
+type TestPreparedLeg struct {
+	Class string    `query:"class,default=std"`
+	Date  time.Time `query:"date,unix,default=tomorrow"`
+}
+
+func TestPrepare(t *testing.T) {
+	assert.Nil(t, mapper.Prepare(&TestDefaultsRequest{}))
+
+	var r struct {
+		Legs []TestPreparedLeg `query:"legs"`
+	}
+	err := mapper.Prepare(&r)
+	assert.EqualError(t, err, "Invalid configuration of field `Date` in mapper_test.TestPreparedLeg: "+
+		"Provided value `tomorrow` for field `Date` is not compatible with time or no format was provided")
+
+	err = mapper.Prepare(r)
+	assert.EqualError(t, err, "Prepare only works with pointers")
+}
@@ -0,0 +1,192 @@
+package mapper
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// field is a struct field read from or written to the query string. Fields
+// of embedded structs, and of named struct fields with the "inline" or
+// "prefix" option, are promoted into their parent.
+type field struct {
+	name  string // query key relative to the struct
+	path  string // Go field path used in error messages
+	index []int
+	typ   reflect.Type
+	tag   string // query tag as written
+	opts  TagOptions
+	depth int
+}
+
+var fieldCache sync.Map // map[reflect.Type][]field
+
+// cachedTypeFields is like typeFields but uses a cache to avoid repeated work.
+func cachedTypeFields(t reflect.Type) []field {
+	if f, ok := fieldCache.Load(t); ok {
+		return f.([]field)
+	}
+	f, _ := fieldCache.LoadOrStore(t, typeFields(t))
+	return f.([]field)
+}
+
+// typeFields returns the fields mapped for the struct type t. Promotion
+// follows the visibility and shadowing rules of encoding/json: a shallower
+// field hides deeper fields with the same key, and fields with the same key
+// at the same depth hide each other.
+func typeFields(t reflect.Type) []field {
+	type queued struct {
+		typ    reflect.Type
+		index  []int
+		prefix string
+		path   string
+	}
+
+	current := []queued{}
+	next := []queued{{typ: t}}
+
+	// Types already visited at an earlier depth, by key prefix.
+	type visit struct {
+		typ    reflect.Type
+		prefix string
+	}
+	visited := map[visit]bool{}
+
+	var fields []field
+	for depth := 0; len(next) > 0; depth++ {
+		current, next = next, current[:0]
+
+		for _, q := range current {
+			if visited[visit{q.typ, q.prefix}] {
+				continue
+			}
+			visited[visit{q.typ, q.prefix}] = true
+
+			for i := 0; i < q.typ.NumField(); i++ {
+				sf := q.typ.Field(i)
+
+				// Ignore unexported fields, but keep looking into
+				// unexported embedded structs for exported fields.
+				if sf.Anonymous {
+					ft := sf.Type
+					if ft.Kind() == reflect.Ptr {
+						ft = ft.Elem()
+					}
+					if sf.PkgPath != "" && ft.Kind() != reflect.Struct {
+						continue
+					}
+				} else if sf.PkgPath != "" {
+					continue
+				}
+
+				tag := sf.Tag.Get("query")
+				if tag == "-" {
+					continue
+				}
+
+				name, opts := TagOptionsFromString(tag)
+
+				index := make([]int, len(q.index)+1)
+				copy(index, q.index)
+				index[len(q.index)] = i
+
+				path := sf.Name
+				if q.path != "" {
+					path = q.path + "." + sf.Name
+				}
+
+				ft := sf.Type
+				if ft.Kind() == reflect.Ptr {
+					ft = ft.Elem()
+				}
+
+				promote := opts.Contains("inline") || opts.Contains("prefix") || (sf.Anonymous && name == "")
+				if promote && isStructType(ft) {
+					// An unexported embedded pointer cannot be allocated.
+					if sf.PkgPath != "" && sf.Type.Kind() == reflect.Ptr {
+						continue
+					}
+
+					next = append(next, queued{
+						typ:    ft,
+						index:  index,
+						prefix: q.prefix + opts["prefix"],
+						path:   path,
+					})
+					continue
+				}
+
+				if name == "" || sf.PkgPath != "" {
+					continue
+				}
+
+				fields = append(fields, field{
+					name:  q.prefix + name,
+					path:  path,
+					index: index,
+					typ:   sf.Type,
+					tag:   tag,
+					opts:  opts,
+					depth: depth,
+				})
+			}
+		}
+	}
+
+	// Keep the dominant field for each key, dropping ambiguous ones.
+	sort.SliceStable(fields, func(i, j int) bool {
+		if fields[i].name != fields[j].name {
+			return fields[i].name < fields[j].name
+		}
+		return fields[i].depth < fields[j].depth
+	})
+
+	out := fields[:0]
+	for advance, i := 0, 0; i < len(fields); i += advance {
+		name := fields[i].name
+		for advance = 1; i+advance < len(fields); advance++ {
+			if fields[i+advance].name != name {
+				break
+			}
+		}
+		if advance == 1 || fields[i].depth < fields[i+1].depth {
+			out = append(out, fields[i])
+		}
+	}
+	fields = out
+
+	// Restore declaration order.
+	sort.Slice(fields, func(i, j int) bool {
+		return lessIndex(fields[i].index, fields[j].index)
+	})
+
+	return fields
+}
+
+func lessIndex(a, b []int) bool {
+	for k := 0; k < len(a) && k < len(b); k++ {
+		if a[k] != b[k] {
+			return a[k] < b[k]
+		}
+	}
+	return len(a) < len(b)
+}
+
+// fieldByIndex returns the field of v at index. Nil embedded pointers on the
+// way are allocated when alloc is true; otherwise the returned value is not
+// valid.
+func fieldByIndex(v reflect.Value, index []int, alloc bool) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !alloc {
+					return reflect.Value{}
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
@@ -0,0 +1,112 @@
+package mapper_test
+
+import (
+	"github.com/assertis/url-mapper"
+	"github.com/stretchr/testify/assert"
+	"net/url"
+	"testing"
+)
+
+type TestPagination struct {
+	Page    int `query:"page"`
+	PerPage int `query:"per_page"`
+}
+
+type TestLocale struct {
+	Language string `query:"lang"`
+	Page     int    `query:"page"`
+}
+
+type testHidden struct {
+	Channel string `query:"channel"`
+}
+
+type TestEmbeddedRequest struct {
+	TestPagination
+	*TestLocale
+	testHidden
+	Origin string `query:"o"`
+}
+
+func TestMappingEmbeddedStructs(t *testing.T) {
+	var r = TestEmbeddedRequest{}
+
+	values, err := url.ParseQuery("page=2&per_page=50&lang=en&channel=web&o=TBW")
+	assert.Nil(t, err)
+
+	err = mapper.Unmarshal(values, &r)
+	assert.Nil(t, err)
+
+	// `page` is ambiguous between TestPagination and TestLocale.
+	assert.Equal(t, 0, r.TestPagination.Page)
+	assert.Equal(t, 50, r.PerPage)
+	if assert.NotNil(t, r.TestLocale) {
+		assert.Equal(t, "en", r.Language)
+	}
+	assert.Equal(t, "web", r.Channel)
+	assert.Equal(t, "TBW", r.Origin)
+}
+
+type TestShadowRequest struct {
+	TestPagination
+	Page string `query:"page"`
+}
+
+func TestMappingEmbeddedShadowing(t *testing.T) {
+	var r = TestShadowRequest{}
+
+	values, err := url.ParseQuery("page=first&per_page=10")
+	assert.Nil(t, err)
+
+	err = mapper.Unmarshal(values, &r)
+	assert.Nil(t, err)
+
+	assert.Equal(t, "first", r.Page)
+	assert.Equal(t, 0, r.TestPagination.Page)
+	assert.Equal(t, 10, r.PerPage)
+}
+
+func TestMappingEmbeddedNilPointer(t *testing.T) {
+	var r = TestEmbeddedRequest{}
+
+	values, err := url.ParseQuery("o=TBW")
+	assert.Nil(t, err)
+
+	err = mapper.Unmarshal(values, &r)
+	assert.Nil(t, err)
+	assert.Nil(t, r.TestLocale)
+}
+
+type TestInlineRequest struct {
+	Paging  TestPagination `query:",inline"`
+	Outward TestLeg        `query:",prefix=out_"`
+	Inward  *TestLeg       `query:",prefix=in_"`
+}
+
+func TestMappingInlineAndPrefix(t *testing.T) {
+	var r = TestInlineRequest{}
+
+	values, err := url.ParseQuery("page=3&out_station=TBW&in_station=LBG&in_date=X")
+	assert.Nil(t, err)
+
+	err = mapper.Unmarshal(values, &r)
+	assert.EqualError(t, err, "Provided value `X` for field `Inward.Date` is not compatible with time or no format was provided")
+
+	values, err = url.ParseQuery("page=3&out_station=TBW&in_station=LBG")
+	assert.Nil(t, err)
+
+	err = mapper.Unmarshal(values, &r)
+	assert.Nil(t, err)
+
+	assert.Equal(t, 3, r.Paging.Page)
+	assert.Equal(t, "TBW", r.Outward.Station)
+	if assert.NotNil(t, r.Inward) {
+		assert.Equal(t, "LBG", r.Inward.Station)
+	}
+
+	out, err := mapper.Marshal(r)
+	assert.Nil(t, err)
+	assert.Equal(t, "3", out.Get("page"))
+	assert.Equal(t, "TBW", out.Get("out_station"))
+	assert.Equal(t, "LBG", out.Get("in_station"))
+}
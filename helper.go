@@ -1,7 +1,9 @@
 package mapper
 
 import (
+	"errors"
 	"reflect"
+	"strings"
 	"time"
 )
 
@@ -33,3 +35,110 @@ func isEmptyValue(v reflect.Value) bool {
 
 	return false
 }
+
+// indirect walks down v allocating pointers as needed, until it gets to a
+// non-pointer value or to a pointer type with a registered converter.
+func indirect(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Ptr {
+		if _, ok := converterFor(v.Type()); ok {
+			break
+		}
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+
+	return v
+}
+
+// joinKey builds the query key of a nested field.
+func joinKey(prefix, separator, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + separator + name
+}
+
+// isStructType reports whether t, or the type it points to, is a struct that
+// is decoded field by field.
+func isStructType(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct && t != timeType && !isCustomType(t)
+}
+
+// isNestedType reports whether values of type t are decoded from keys below
+// their own key rather than from the key itself.
+func isNestedType(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if isCustomType(t) {
+		return false
+	}
+
+	switch t.Kind() {
+	case reflect.Map:
+		return true
+	case reflect.Slice:
+		return isStructType(t.Elem())
+	}
+	return isStructType(t)
+}
+
+// parseBool accepts the usual spellings of true and false, in any case. With
+// the "checkbox" option an empty value, sent for a ticked box without a
+// value attribute, is true.
+func parseBool(value string, opts TagOptions) (bool, error) {
+	switch strings.ToLower(value) {
+	case "1", "t", "true", "y", "yes", "on":
+		return true, nil
+	case "0", "f", "false", "n", "no", "off":
+		return false, nil
+	case "":
+		if opts.Contains("checkbox") {
+			return true, nil
+		}
+	}
+
+	return false, errors.New("invalid boolean")
+}
+
+// separatorFromOptions returns the separator named by the "sep" option, or an
+// empty string when values are not delimited. The names follow the OpenAPI
+// styles: `form` and `comma` for commas, `space`/`spaceDelimited` and
+// `pipe`/`pipeDelimited`. Any other value is used as is.
+func separatorFromOptions(opts TagOptions) string {
+	switch sep := opts["sep"]; strings.ToLower(sep) {
+	case "comma", "form":
+		return ","
+	case "space", "spacedelimited":
+		return " "
+	case "pipe", "pipedelimited":
+		return "|"
+	default:
+		return sep
+	}
+}
+
+// splitValues splits every value on the separator given in the "sep"
+// option, trimming the parts and dropping empty ones.
+func splitValues(values []string, opts TagOptions) []string {
+	sep := separatorFromOptions(opts)
+	if sep == "" {
+		return values
+	}
+
+	var parts []string
+	for _, value := range values {
+		for _, part := range strings.Split(value, sep) {
+			if part = strings.TrimSpace(part); part != "" {
+				parts = append(parts, part)
+			}
+		}
+	}
+	return parts
+}
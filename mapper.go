@@ -10,16 +10,44 @@
 //		ReturnDate time.Time `query:"inward,omitempty"`
 //	}
 //
+// Struct fields are decoded from keys prefixed with the field's own key, so
+//	Outward Leg `query:"outward"`
+// is populated from `outward.station=TBW&outward.date=...`. Bracketed keys
+// such as `passengers[0][age]=30` are understood as well, and fill slices of
+// structs and maps.
+//
+// The "omitempty" option leaves empty fields out of Marshal's output. It has
+// no effect on decoding.
+//
+// Fields of embedded structs are promoted into the parent following the
+// rules of encoding/json. Named struct fields can be promoted as well with
+// the "inline" option, or with "prefix=out_" to read `out_station`.
+//
+// Decoded values are validated with the "required", "min", "max", "len",
+// "pattern" and "oneof" options, e.g.
+//	Adults int `query:"adults,min=1,max=9"`
+// Options such as "gtfield=OutwardDate" and "required_without=Railcard"
+// relate fields of the same struct to each other. Structs implementing
+// Validator are checked last, innermost first. Failures are collected per
+// field and returned as ValidationErrors.
+//
+// Absent keys can be given a value with the "default" option, e.g.
+// "default=1", "default=std|first" for slices or "default=now" for times.
+// Defaults are parsed once; one that does not parse is a ConfigError, which
+// Prepare reports up front.
+//
 
 package mapper
 
 import (
 	"errors"
 	"fmt"
-	"github.com/asaskevich/govalidator"
+	"math"
 	"net/url"
 	"reflect"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -28,114 +56,613 @@ var (
 	wrongIntType          = "Provided value `%s` for field `%s` is not an integer"
 	wrongTimeType         = "Provided value `%s` for field `%s` is not compatible with time or no format was provided"
 	onlyPositiveInt       = "Negative value `%s` for field `%s` is not supported"
+	intOutOfRange         = "Provided value `%s` for field `%s` is out of range [%d, %d]"
+	uintOutOfRange        = "Provided value `%s` for field `%s` is out of range [0, %d]"
+	unknownIntBase        = "Unknown base `%s` for field `%s`"
+	wrongBoolType         = "Provided value `%s` for field `%s` is not a boolean"
+	wrongFloatType        = "Provided value `%s` for field `%s` is not a number"
+	floatOutOfRange       = "Provided value `%s` for field `%s` is out of range"
+	nonFiniteFloat        = "Provided value `%s` for field `%s` is not a finite number"
+	wrongArrayLength      = "Field `%s` expects %d values, %d were provided"
+	unknownArrayPolicy    = "Unknown array policy `%s` for field `%s`"
+	maxDepthExceeded      = "Field `%s` exceeds the maximum nesting depth of %d"
+	wrongCustomType       = "Provided value `%s` for field `%s` is not valid: %s"
+	maxMapEntriesExceeded = "Field `%s` exceeds the maximum of %d map entries"
+)
+
+const (
+	// DefaultSeparator joins the key of a struct field with the keys of its
+	// nested fields, e.g. `outward.station`.
+	DefaultSeparator = "."
+	// DefaultMaxDepth is the number of nested struct levels followed by default.
+	DefaultMaxDepth = 10
+	// DefaultMaxIndex is the highest slice index accepted by default.
+	DefaultMaxIndex = 1000
+	// DefaultMaxMapEntries is the number of entries a map field may receive
+	// by default.
+	DefaultMaxMapEntries = 100
 )
 
+// Decoder maps url.Values onto structs. The zero value is ready to use and
+// falls back to DefaultSeparator, DefaultMaxDepth, DefaultMaxIndex and
+// DefaultMaxMapEntries.
+type Decoder struct {
+	// Separator is placed between the key of a struct field and the keys
+	// of its nested fields.
+	Separator string
+
+	// MaxDepth limits how many levels of nested structs are decoded, and
+	// how many bracketed segments a key such as `a[b][c]` may have.
+	MaxDepth int
+
+	// MaxIndex is the highest index accepted in keys such as
+	// `passengers[3][age]` or `passengers.3.age`.
+	MaxIndex int
+
+	// MaxMapEntries limits the number of entries decoded into a single map
+	// field.
+	MaxMapEntries int
+
+	// Location is the time zone time fields are decoded into, unless their
+	// tag has a "tz" option. Layouts without a zone offset are read in it.
+	// When nil, Unix timestamps are in the local zone and layouts in UTC.
+	Location *time.Location
+}
+
+var defaultDecoder = &Decoder{}
+
+// Unmarshal maps values onto the struct pointed to by v using the default
+// Decoder.
 func Unmarshal(path url.Values, v interface{}) error {
+	return defaultDecoder.Unmarshal(path, v)
+}
+
+// Unmarshal maps values onto the struct pointed to by v.
+func (d *Decoder) Unmarshal(values url.Values, v interface{}) error {
 	val := reflect.ValueOf(v)
 	if val.Kind() != reflect.Ptr {
 		return errWrongUnmarshalType
 	}
 
-	return mapToStruct(path, val.Elem())
+	if err := d.prepared(val.Elem().Type()); err != nil {
+		return err
+	}
+
+	values, err := d.expandBrackets(values)
+	if err != nil {
+		return err
+	}
+
+	return d.mapToStruct(values, val.Elem(), "", "", 0)
 }
 
-func mapToStruct(values url.Values, v reflect.Value) error {
-	mapToType := v.Type() // must be struct
-	for i := 0; i < mapToType.NumField(); i++ {
-		mapToField := mapToType.Field(i)
+func (d *Decoder) separator() string {
+	if d.Separator == "" {
+		return DefaultSeparator
+	}
+	return d.Separator
+}
 
-		// Ignore unexported fields
-		if mapToField.PkgPath != "" && !mapToField.Anonymous {
-			continue
+func (d *Decoder) maxDepth() int {
+	if d.MaxDepth <= 0 {
+		return DefaultMaxDepth
+	}
+	return d.MaxDepth
+}
+
+func (d *Decoder) maxIndex() int {
+	if d.MaxIndex <= 0 {
+		return DefaultMaxIndex
+	}
+	return d.MaxIndex
+}
+
+func (d *Decoder) maxMapEntries() int {
+	if d.MaxMapEntries <= 0 {
+		return DefaultMaxMapEntries
+	}
+	return d.MaxMapEntries
+}
+
+func (d *Decoder) key(prefix, name string) string {
+	return joinKey(prefix, d.separator(), name)
+}
+
+// mapToStruct decodes values onto the struct v. The prefix is the query key
+// of the struct itself and path is its Go field path, both empty at the top
+// level.
+//
+// Fields failing validation do not stop decoding; they are collected and
+// returned together as ValidationErrors once the whole struct is decoded.
+func (d *Decoder) mapToStruct(values url.Values, v reflect.Value, prefix, path string, depth int) error {
+	var errs ValidationErrors
+	for _, f := range cachedTypeFields(v.Type()) { // v must be struct
+		fieldName := f.path
+		if path != "" {
+			fieldName = path + "." + fieldName
 		}
 
-		mapToValue := v.Field(i)
+		key := d.key(prefix, f.name)
 
-		tag := mapToField.Tag.Get("query")
-		if tag == "-" {
-			continue
+		// Keys with a default are absent when the default applies.
+		useDefault := f.opts.Contains("default") && !d.present(values, key, f.typ, f.opts)
+
+		mapToValue := fieldByIndex(v, f.index, false)
+		if !mapToValue.IsValid() && (useDefault || d.present(values, key, f.typ, f.opts)) {
+			// Promoted through a nil embedded pointer
+			mapToValue = fieldByIndex(v, f.index, true)
 		}
 
-		name, opts := TagOptionsFromString(tag)
+		settable := mapToValue.IsValid() && mapToValue.CanSet()
+		switch {
+		case settable && useDefault:
+			if err := d.setDefault(v.Type(), f, mapToValue, fieldName); err != nil {
+				return err
+			}
+		case settable:
+			if err := collect(&errs, d.decodeField(values, mapToValue, key, fieldName, f.opts, depth)); err != nil {
+				return err
+			}
+		}
 
-		if opts.Contains("omitempty") && isEmptyValue(mapToValue) {
-			continue
+		fieldErrs, err := d.validateField(values, mapToValue, key, fieldName, f.typ, f.opts)
+		if err != nil {
+			return err
 		}
+		errs = append(errs, fieldErrs...)
+	}
+
+	crossErrs, err := d.validateCrossFields(values, v, prefix, path)
+	if err != nil {
+		return err
+	}
+	errs = append(errs, crossErrs...)
+	errs = append(errs, validateStruct(v, prefix, path)...)
+
+	return errs.err()
+}
 
-		for mapToValue.Kind() == reflect.Ptr {
-			if mapToValue.IsNil() {
-				break
+// decodeField decodes the values found under key into v, recursing into
+// nested structs, slices of structs and maps. Nil pointers are allocated
+// only when there is something to decode into them.
+func (d *Decoder) decodeField(values url.Values, v reflect.Value, key, fieldName string, opts TagOptions, depth int) error {
+	_, registered := converterFor(v.Type())
+
+	if v.Kind() == reflect.Ptr && !registered {
+		if v.IsNil() {
+			if !d.present(values, key, v.Type(), opts) {
+				return nil
 			}
-			mapToValue = mapToValue.Elem()
+			v.Set(reflect.New(v.Type().Elem()))
 		}
+		return d.decodeField(values, v.Elem(), key, fieldName, opts, depth)
+	}
 
-		if mapToValue.IsValid() && mapToValue.CanSet() {
-			value := values.Get(name)
+	if u, ok := queryUnmarshaler(v); ok && !registered {
+		if values.Get(key) == "" {
+			return nil
+		}
+		if err := u.UnmarshalQuery(values[key]); err != nil {
+			return errors.New(fmt.Sprintf(wrongCustomType, strings.Join(values[key], ","), fieldName, err))
+		}
+		return nil
+	}
 
-			if values.Get(name) == "" {
-				continue
+	if isNestedType(v.Type()) {
+		if depth >= d.maxDepth() {
+			// Only input reaching this deep is an error, not the type.
+			if !d.present(values, key, v.Type(), opts) {
+				return nil
 			}
+			return errors.New(fmt.Sprintf(maxDepthExceeded, fieldName, d.maxDepth()))
+		}
+
+		switch v.Kind() {
+		case reflect.Struct:
+			return d.mapToStruct(values, v, key, fieldName, depth+1)
+		case reflect.Slice:
+			return d.setStructSlice(values, v, key, fieldName, depth+1)
+		case reflect.Map:
+			return d.setMap(values, v, key, fieldName, opts, depth+1)
+		}
+	}
+
+	if !d.present(values, key, v.Type(), opts) {
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		list, err := d.listValues(values, key, opts)
+		if err != nil {
+			return err
+		}
+		if len(list) == 0 {
+			return nil
+		}
+		if v.Kind() == reflect.Slice {
+			return d.setSlice(v, list, fieldName, opts)
+		}
+		return d.setArray(v, list, fieldName, opts)
+	}
+
+	return d.setValue(v, values.Get(key), fieldName, opts)
+}
+
+// listValues returns the values of a slice or array field: those of its own
+// key, split with the "sep" option, followed by those of indexed keys such as
+// `ids.0` or `ids[0]` in index order.
+func (d *Decoder) listValues(values url.Values, key string, opts TagOptions) ([]string, error) {
+	list := splitValues(values[key], opts)
+
+	indices, err := d.indices(values, key, false)
+	if err != nil {
+		return nil, err
+	}
+	for _, index := range indices {
+		list = append(list, splitValues(values[d.key(key, strconv.Itoa(index))], opts)...)
+	}
+	return list, nil
+}
 
-			// Time?
-			if mapToValue.Type() == reflect.TypeOf(time.Time{}) {
-				if opts.Contains("rfc3339") && govalidator.IsRFC3339(value) {
-					t, err := time.Parse(time.RFC3339, value)
-					if err != nil {
-						return err
-					}
-					mapToValue.Set(reflect.ValueOf(t))
-				} else if opts.Contains("unix") && govalidator.IsInt(value) {
-					i, _ := strconv.Atoi(value)
-
-					t := time.Unix(int64(i), 0)
-					mapToValue.Set(reflect.ValueOf(t))
-				} else {
-					return errors.New(fmt.Sprintf(wrongTimeType, value, mapToField.Name))
-				}
-
-				continue
+// present reports whether values hold anything to decode into a field of
+// type t stored under key. With the "checkbox" option a key without a value
+// counts as present. Slices and arrays are also present with indexed keys
+// only.
+func (d *Decoder) present(values url.Values, key string, t reflect.Type, opts TagOptions) bool {
+	if !isNestedType(t) {
+		if _, ok := values[key]; ok && opts.Contains("checkbox") {
+			return true
+		}
+		if values.Get(key) != "" {
+			return true
+		}
+
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if t.Kind() != reflect.Slice && t.Kind() != reflect.Array {
+			return false
+		}
+	}
+
+	prefix := key + d.separator()
+	for k := range values {
+		if strings.HasPrefix(k, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// setMap decodes every key found below prefix into the map v, e.g.
+// `meta.channel=web` or `meta[channel]=web` sets v["channel"]. Map keys and
+// values are converted like any other field.
+func (d *Decoder) setMap(values url.Values, v reflect.Value, prefix, path string, opts TagOptions, depth int) error {
+	mapType := v.Type()
+	elemIsNested := isNestedType(mapType.Elem())
+
+	seen := make(map[string]bool)
+	var entries []string
+	for key := range values {
+		if !strings.HasPrefix(key, prefix+d.separator()) {
+			continue
+		}
+
+		entry := key[len(prefix)+len(d.separator()):]
+		if elemIsNested {
+			if i := strings.Index(entry, d.separator()); i >= 0 {
+				entry = entry[:i]
 			}
+		}
+		if entry == "" || seen[entry] {
+			continue
+		}
+
+		seen[entry] = true
+		entries = append(entries, entry)
+		if len(entries) > d.maxMapEntries() {
+			return errors.New(fmt.Sprintf(maxMapEntriesExceeded, path, d.maxMapEntries()))
+		}
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+
+	if v.IsNil() {
+		v.Set(reflect.MakeMap(mapType))
+	}
+
+	var errs ValidationErrors
+	sort.Strings(entries)
+	for _, entry := range entries {
+		entryName := fmt.Sprintf("%s[%s]", path, entry)
+
+		mapKey := reflect.New(mapType.Key()).Elem()
+		if err := d.setValue(mapKey, entry, entryName, nil); err != nil {
+			return err
+		}
+
+		mapValue := reflect.New(mapType.Elem()).Elem()
+		if existing := v.MapIndex(mapKey); existing.IsValid() {
+			mapValue.Set(existing)
+		}
+
+		if err := collect(&errs, d.decodeField(values, indirect(mapValue), d.key(prefix, entry), entryName, opts, depth)); err != nil {
+			return err
+		}
+		v.SetMapIndex(mapKey, mapValue)
+	}
+
+	return errs.err()
+}
+
+// setSlice decodes every provided value into a new slice, converting each
+// element the same way as a scalar field. Values are taken from repeated keys
+// and, with the "sep" option, from delimited lists such as `TBW,LBG`.
+func (d *Decoder) setSlice(v reflect.Value, values []string, fieldName string, opts TagOptions) error {
+	slice := reflect.MakeSlice(v.Type(), len(values), len(values))
+	for i, value := range values {
+		elemName := fmt.Sprintf("%s[%d]", fieldName, i)
+		if err := d.setValue(indirect(slice.Index(i)), value, elemName, opts); err != nil {
+			return err
+		}
+	}
+
+	v.Set(slice)
+	return nil
+}
+
+// setStructSlice decodes a slice of structs from indexed keys such as
+// `passengers.0.age`. Elements are kept in index order; gaps in the indices
+// do not produce empty elements.
+func (d *Decoder) setStructSlice(values url.Values, v reflect.Value, prefix, path string, depth int) error {
+	indices, err := d.indices(values, prefix, true)
+	if err != nil {
+		return err
+	}
+	if len(indices) == 0 {
+		return nil
+	}
+
+	var errs ValidationErrors
+	slice := reflect.MakeSlice(v.Type(), len(indices), len(indices))
+	for i, index := range indices {
+		elemKey := d.key(prefix, strconv.Itoa(index))
+		elemPath := fmt.Sprintf("%s[%d]", path, i)
+		if err := collect(&errs, d.mapToStruct(values, indirect(slice.Index(i)), elemKey, elemPath, depth)); err != nil {
+			return err
+		}
+	}
+
+	v.Set(slice)
+	return errs.err()
+}
+
+// indices returns the sorted, distinct indices found directly below prefix.
+// With nested, only indices with keys below them count, as in
+// `passengers.0.age`; otherwise only keys such as `ids.0` do. Indices not
+// written in their canonical form, such as `01`, are ignored.
+func (d *Decoder) indices(values url.Values, prefix string, nested bool) ([]int, error) {
+	prefix += d.separator()
+
+	seen := make(map[int]bool)
+	var indices []int
+	for key := range values {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+
+		segment, rest := key[len(prefix):], ""
+		if i := strings.Index(segment, d.separator()); i >= 0 {
+			segment, rest = segment[:i], segment[i+len(d.separator()):]
+		}
+		if nested == (rest == "") {
+			continue
+		}
+
+		index, err := strconv.Atoi(segment)
+		if err != nil || index < 0 || strconv.Itoa(index) != segment || seen[index] {
+			continue
+		}
+		if index > d.maxIndex() {
+			return nil, errors.New(fmt.Sprintf(bracketIndexExceeded, key, d.maxIndex()))
+		}
+
+		seen[index] = true
+		indices = append(indices, index)
+	}
+
+	sort.Ints(indices)
+	return indices, nil
+}
+
+// setArray decodes the provided values into a fixed-size array. The "array"
+// tag option controls what happens when the number of values does not match
+// the array length:
+//
+//	array=error     both too many and too few values are an error (default)
+//	array=truncate  extra values are dropped, missing values are an error
+//	array=zero      missing elements are left as zero values, extra values are an error
+func (d *Decoder) setArray(v reflect.Value, values []string, fieldName string, opts TagOptions) error {
+	policy := opts["array"]
+	switch policy {
+	case "", "error", "truncate", "zero":
+	default:
+		return errors.New(fmt.Sprintf(unknownArrayPolicy, policy, fieldName))
+	}
+
+	length := v.Len()
+	switch {
+	case len(values) > length && policy == "truncate":
+		values = values[:length]
+	case len(values) < length && policy == "zero":
+	case len(values) != length:
+		return errors.New(fmt.Sprintf(wrongArrayLength, fieldName, length, len(values)))
+	}
 
-			switch mapToValue.Kind() {
-			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-				if !govalidator.IsInt(value) {
-					return errors.New(fmt.Sprintf(wrongIntType, value, mapToField.Name))
-				}
-
-				i, err := strconv.Atoi(value)
-				if err != nil {
-					return err
-				}
-				mapToValue.SetInt(int64(i))
-			case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-				if !govalidator.IsInt(value) {
-					return errors.New(fmt.Sprintf(wrongIntType, value, mapToField.Name))
-				}
-
-				i, err := strconv.Atoi(value)
-				if err != nil {
-					return err
-				}
-				if i < 0 {
-					return errors.New(fmt.Sprintf(onlyPositiveInt, value, mapToField.Name))
-				}
-				mapToValue.SetUint(uint64(i))
-			case reflect.Bool:
-				if value == "1" {
-					mapToValue.SetBool(true)
-				} else {
-					mapToValue.SetBool(false)
-				}
-			case reflect.String:
-				mapToValue.SetString(value)
-			case reflect.Slice, reflect.Array:
-				if len(values[name]) == 0 {
-					mapToValue.Set(reflect.MakeSlice(mapToValue.Type(), 0, 0))
-				} else {
-					mapToValue.Set(reflect.ValueOf(values[name]))
-				}
+	array := reflect.New(v.Type()).Elem()
+	for i, value := range values {
+		elemName := fmt.Sprintf("%s[%d]", fieldName, i)
+		if err := d.setValue(indirect(array.Index(i)), value, elemName, opts); err != nil {
+			return err
+		}
+	}
+
+	v.Set(array)
+	return nil
+}
+
+// intBase returns the base given in the "base" option. Without a value, as
+// in `query:"id,base"`, the base is implied by the prefix of the value
+// (`0x`, `0o` or `0b`) and underscores may separate digits. A leading zero
+// alone does not make the value octal, see decimalLeadingZeros.
+func intBase(fieldName string, opts TagOptions) (int, error) {
+	value, ok := opts["base"]
+	switch {
+	case !ok:
+		return 10, nil
+	case value == "":
+		return 0, nil
+	}
+
+	base, err := strconv.Atoi(value)
+	if err != nil || base < 2 || base > 36 {
+		return 0, errors.New(fmt.Sprintf(unknownIntBase, value, fieldName))
+	}
+	return base, nil
+}
+
+// intDigits returns the digits of an integer field's value and the base to
+// parse them in. With the "money" option a decimal amount is turned into
+// minor units first.
+func intDigits(value string, fieldName string, opts TagOptions) (string, int, error) {
+	if opts.Contains("money") {
+		digits, err := minorUnits(value, fieldName, opts)
+		return digits, 10, err
+	}
+
+	base, err := intBase(fieldName, opts)
+	if base == 0 {
+		value = decimalLeadingZeros(value)
+	}
+	return value, base, err
+}
+
+// decimalLeadingZeros drops the leading zeros of a value parsed with base 0,
+// which strconv would otherwise read as an octal number: `010` is ten, only
+// `0o10` is eight.
+func decimalLeadingZeros(value string) string {
+	sign, digits := "", value
+	if strings.HasPrefix(digits, "-") || strings.HasPrefix(digits, "+") {
+		sign, digits = digits[:1], digits[1:]
+	}
+	if len(digits) < 2 || digits[0] != '0' || strings.ContainsRune("xXoObB", rune(digits[1])) {
+		return value
+	}
+
+	digits = strings.TrimPrefix(strings.TrimLeft(digits, "0"), "_")
+	if digits == "" {
+		digits = "0"
+	}
+	return sign + digits
+}
+
+// setValue converts a single query string value into v.
+func (d *Decoder) setValue(v reflect.Value, value string, fieldName string, opts TagOptions) error {
+	if ok, err := convert(v, value, fieldName); ok {
+		return err
+	}
+
+	if u, ok := queryUnmarshaler(v); ok {
+		if err := u.UnmarshalQuery([]string{value}); err != nil {
+			return errors.New(fmt.Sprintf(wrongCustomType, value, fieldName, err))
+		}
+		return nil
+	}
+
+	if ok, err := setBig(v, value, fieldName, opts); ok {
+		return err
+	}
+
+	if u, ok := textUnmarshaler(v); ok {
+		if err := u.UnmarshalText([]byte(value)); err != nil {
+			return errors.New(fmt.Sprintf(wrongCustomType, value, fieldName, err))
+		}
+		return nil
+	}
+
+	// Time?
+	if v.Type() == timeType {
+		t, err := d.parseTime(value, fieldName, opts)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	if v.Type() == durationType {
+		d, err := parseDuration(value, fieldName, opts)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		digits, base, err := intDigits(value, fieldName, opts)
+		if err != nil {
+			return err
+		}
+
+		bits := v.Type().Bits()
+		i, err := strconv.ParseInt(digits, base, bits)
+		if numErr, ok := err.(*strconv.NumError); ok && numErr.Err == strconv.ErrRange {
+			min, max := -(int64(1) << uint(bits-1)), int64(1)<<uint(bits-1)-1
+			return errors.New(fmt.Sprintf(intOutOfRange, value, fieldName, min, max))
+		} else if err != nil {
+			return errors.New(fmt.Sprintf(wrongIntType, value, fieldName))
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		digits, base, err := intDigits(value, fieldName, opts)
+		if err != nil {
+			return err
+		}
+
+		bits := v.Type().Bits()
+		i, err := strconv.ParseUint(digits, base, bits)
+		if numErr, ok := err.(*strconv.NumError); ok && numErr.Err == strconv.ErrRange {
+			return errors.New(fmt.Sprintf(uintOutOfRange, value, fieldName, ^uint64(0)>>uint(64-bits)))
+		} else if err != nil {
+			if _, signedErr := strconv.ParseInt(digits, base, 64); strings.HasPrefix(digits, "-") && signedErr == nil {
+				return errors.New(fmt.Sprintf(onlyPositiveInt, value, fieldName))
 			}
+			return errors.New(fmt.Sprintf(wrongIntType, value, fieldName))
+		}
+		v.SetUint(i)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, v.Type().Bits())
+		if numErr, ok := err.(*strconv.NumError); ok && numErr.Err == strconv.ErrRange {
+			return errors.New(fmt.Sprintf(floatOutOfRange, value, fieldName))
+		} else if err != nil {
+			return errors.New(fmt.Sprintf(wrongFloatType, value, fieldName))
+		}
+		if (math.IsNaN(f) || math.IsInf(f, 0)) && !opts.Contains("nonfinite") {
+			return errors.New(fmt.Sprintf(nonFiniteFloat, value, fieldName))
+		}
+		v.SetFloat(f)
+	case reflect.Bool:
+		b, err := parseBool(value, opts)
+		if err != nil {
+			return errors.New(fmt.Sprintf(wrongBoolType, value, fieldName))
 		}
+		v.SetBool(b)
+	case reflect.String:
+		v.SetString(value)
 	}
 
 	return nil
@@ -3,6 +3,7 @@ package mapper_test
 import (
 	"github.com/assertis/url-mapper"
 	"github.com/stretchr/testify/assert"
+	"math"
 	"net/url"
 	"testing"
 	"time"
@@ -64,3 +65,491 @@ func TestIncorrectRFC3339Time(t *testing.T) {
 	err = mapper.Unmarshal(values, &r)
 	assert.NotNil(t, err)
 }
+
+type TestSliceRequest struct {
+	Ages      []int       `query:"age"`
+	FareIDs   []uint      `query:"fare"`
+	Flags     []bool      `query:"flag"`
+	Windows   []time.Time `query:"window,unix"`
+	Stations  []string    `query:"station"`
+	Optionals []*int      `query:"optional"`
+}
+
+func TestMappingTypedSlices(t *testing.T) {
+	var r = TestSliceRequest{}
+
+	values, err := url.ParseQuery("age=30&age=4&fare=7&fare=9&flag=1&flag=0&window=1482852746&station=TBW&station=LBG&optional=5")
+	assert.Nil(t, err)
+
+	err = mapper.Unmarshal(values, &r)
+	assert.Nil(t, err)
+
+	assert.Equal(t, []int{30, 4}, r.Ages)
+	assert.Equal(t, []uint{7, 9}, r.FareIDs)
+	assert.Equal(t, []bool{true, false}, r.Flags)
+	assert.Equal(t, []time.Time{time.Unix(1482852746, 0)}, r.Windows)
+	assert.Equal(t, []string{"TBW", "LBG"}, r.Stations)
+	assert.Len(t, r.Optionals, 1)
+	assert.Equal(t, 5, *r.Optionals[0])
+}
+
+func TestIncorrectSliceElement(t *testing.T) {
+	var r = TestSliceRequest{}
+
+	values, err := url.ParseQuery("age=30&age=X")
+	assert.Nil(t, err)
+
+	err = mapper.Unmarshal(values, &r)
+	assert.EqualError(t, err, "Provided value `X` for field `Ages[1]` is not an integer")
+
+	values, err = url.ParseQuery("fare=1&fare=2&fare=-3")
+	assert.Nil(t, err)
+
+	err = mapper.Unmarshal(values, &r)
+	assert.EqualError(t, err, "Negative value `-3` for field `FareIDs[2]` is not supported")
+}
+
+type TestArrayRequest struct {
+	Legs     [2]string `query:"leg"`
+	Counts   [3]int    `query:"count,array=zero"`
+	Priority [2]uint   `query:"priority,array=truncate"`
+}
+
+func TestMappingArrays(t *testing.T) {
+	var r = TestArrayRequest{}
+
+	values, err := url.ParseQuery("leg=TBW&leg=LBG&count=1&count=2&priority=3&priority=4&priority=5")
+	assert.Nil(t, err)
+
+	err = mapper.Unmarshal(values, &r)
+	assert.Nil(t, err)
+
+	assert.Equal(t, [2]string{"TBW", "LBG"}, r.Legs)
+	assert.Equal(t, [3]int{1, 2, 0}, r.Counts)
+	assert.Equal(t, [2]uint{3, 4}, r.Priority)
+}
+
+func TestIncorrectArrayLength(t *testing.T) {
+	var r = TestArrayRequest{}
+
+	values, err := url.ParseQuery("leg=TBW")
+	assert.Nil(t, err)
+
+	err = mapper.Unmarshal(values, &r)
+	assert.EqualError(t, err, "Field `Legs` expects 2 values, 1 were provided")
+
+	values, err = url.ParseQuery("count=1&count=2&count=3&count=4")
+	assert.Nil(t, err)
+
+	err = mapper.Unmarshal(values, &r)
+	assert.NotNil(t, err)
+
+	values, err = url.ParseQuery("priority=X&priority=1")
+	assert.Nil(t, err)
+
+	err = mapper.Unmarshal(values, &r)
+	assert.EqualError(t, err, "Provided value `X` for field `Priority[0]` is not an integer")
+}
+
+type TestFloatRequest struct {
+	Latitude   float64    `query:"lat"`
+	Multiplier float32    `query:"multiplier"`
+	Discount   float64    `query:"discount,nonfinite"`
+	BoundBox   [4]float64 `query:"bbox"`
+}
+
+func TestMappingFloats(t *testing.T) {
+	var r = TestFloatRequest{}
+
+	values, err := url.ParseQuery("lat=51.5074&multiplier=1.25&discount=-Inf&bbox=1&bbox=2.5&bbox=-3&bbox=4e2")
+	assert.Nil(t, err)
+
+	err = mapper.Unmarshal(values, &r)
+	assert.Nil(t, err)
+
+	assert.Equal(t, 51.5074, r.Latitude)
+	assert.Equal(t, float32(1.25), r.Multiplier)
+	assert.True(t, math.IsInf(r.Discount, -1))
+	assert.Equal(t, [4]float64{1, 2.5, -3, 400}, r.BoundBox)
+}
+
+func TestIncorrectFloat(t *testing.T) {
+	var r = TestFloatRequest{}
+
+	values, err := url.ParseQuery("lat=north")
+	assert.Nil(t, err)
+
+	err = mapper.Unmarshal(values, &r)
+	assert.EqualError(t, err, "Provided value `north` for field `Latitude` is not a number")
+
+	values, err = url.ParseQuery("lat=NaN")
+	assert.Nil(t, err)
+
+	err = mapper.Unmarshal(values, &r)
+	assert.EqualError(t, err, "Provided value `NaN` for field `Latitude` is not a finite number")
+
+	values, err = url.ParseQuery("multiplier=1e40")
+	assert.Nil(t, err)
+
+	err = mapper.Unmarshal(values, &r)
+	assert.EqualError(t, err, "Provided value `1e40` for field `Multiplier` is out of range")
+}
+
+type TestLeg struct {
+	Station string    `query:"station"`
+	Date    time.Time `query:"date,unix"`
+}
+
+type TestJourneyRequest struct {
+	Adults  int     `query:"adults"`
+	Outward TestLeg `query:"outward"`
+	Inward  TestLeg `query:"inward"`
+}
+
+func TestMappingNestedStructs(t *testing.T) {
+	var r = TestJourneyRequest{}
+
+	values, err := url.ParseQuery("adults=2&outward.station=TBW&outward.date=1482852746&inward.station=LBG")
+	assert.Nil(t, err)
+
+	err = mapper.Unmarshal(values, &r)
+	assert.Nil(t, err)
+
+	assert.Equal(t, 2, r.Adults)
+	assert.Equal(t, "TBW", r.Outward.Station)
+	assert.Equal(t, time.Unix(1482852746, 0), r.Outward.Date)
+	assert.Equal(t, "LBG", r.Inward.Station)
+	assert.True(t, r.Inward.Date.IsZero())
+
+	values, err = url.ParseQuery("outward.date=X")
+	assert.Nil(t, err)
+
+	err = mapper.Unmarshal(values, &r)
+	assert.EqualError(t, err, "Provided value `X` for field `Outward.Date` is not compatible with time or no format was provided")
+}
+
+func TestMappingNestedStructsCustomSeparator(t *testing.T) {
+	var r = TestJourneyRequest{}
+
+	values, err := url.ParseQuery("outward_station=TBW&outward.station=LBG")
+	assert.Nil(t, err)
+
+	decoder := mapper.Decoder{Separator: "_"}
+	err = decoder.Unmarshal(values, &r)
+	assert.Nil(t, err)
+
+	assert.Equal(t, "TBW", r.Outward.Station)
+}
+
+type TestDeepRequest struct {
+	Journey TestJourneyRequest `query:"journey"`
+}
+
+func TestMappingNestedStructsMaxDepth(t *testing.T) {
+	var r = TestDeepRequest{}
+
+	values, err := url.ParseQuery("journey.outward.station=TBW")
+	assert.Nil(t, err)
+
+	err = mapper.Unmarshal(values, &r)
+	assert.Nil(t, err)
+	assert.Equal(t, "TBW", r.Journey.Outward.Station)
+
+	decoder := mapper.Decoder{MaxDepth: 1}
+	err = decoder.Unmarshal(values, &r)
+	assert.EqualError(t, err, "Field `Journey.Outward` exceeds the maximum nesting depth of 1")
+
+	// Deeper fields that are absent from the query are fine.
+	r = TestDeepRequest{}
+	err = decoder.Unmarshal(url.Values{}, &r)
+	assert.Nil(t, err)
+
+	err = decoder.Unmarshal(url.Values{"journey.adults": {"2"}}, &r)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, r.Journey.Adults)
+}
+
+type TestMapRequest struct {
+	Meta   map[string]string   `query:"meta"`
+	Counts map[string]int      `query:"count"`
+	Legs   map[string]TestLeg  `query:"leg"`
+	Tags   map[string][]string `query:"tag"`
+	ByID   map[int]bool        `query:"id"`
+}
+
+func TestMappingMaps(t *testing.T) {
+	var r = TestMapRequest{}
+
+	values, err := url.ParseQuery("meta[channel]=web&meta.agent=app&count[adult]=2&count[child]=1&leg[out][station]=TBW&tag.a=x&tag.a=y&id[7]=1")
+	assert.Nil(t, err)
+
+	err = mapper.Unmarshal(values, &r)
+	assert.Nil(t, err)
+
+	assert.Equal(t, map[string]string{"channel": "web", "agent": "app"}, r.Meta)
+	assert.Equal(t, map[string]int{"adult": 2, "child": 1}, r.Counts)
+	assert.Equal(t, map[string]TestLeg{"out": {Station: "TBW"}}, r.Legs)
+	assert.Equal(t, map[string][]string{"a": {"x", "y"}}, r.Tags)
+	assert.Equal(t, map[int]bool{7: true}, r.ByID)
+}
+
+func TestIncorrectMapValues(t *testing.T) {
+	var r = TestMapRequest{}
+
+	values, err := url.ParseQuery("count[adult]=two")
+	assert.Nil(t, err)
+
+	err = mapper.Unmarshal(values, &r)
+	assert.EqualError(t, err, "Provided value `two` for field `Counts[adult]` is not an integer")
+
+	values, err = url.ParseQuery("id[seven]=1")
+	assert.Nil(t, err)
+
+	err = mapper.Unmarshal(values, &r)
+	assert.EqualError(t, err, "Provided value `seven` for field `ByID[seven]` is not an integer")
+
+	values, err = url.ParseQuery("meta[a]=1&meta[b]=2&meta[c]=3")
+	assert.Nil(t, err)
+
+	decoder := mapper.Decoder{MaxMapEntries: 2}
+	err = decoder.Unmarshal(values, &r)
+	assert.EqualError(t, err, "Field `Meta` exceeds the maximum of 2 map entries")
+}
+
+type TestPointerRequest struct {
+	Adults     *int       `query:"adults"`
+	Children   *int       `query:"children"`
+	ReturnDate *time.Time `query:"return_date,rfc3339"`
+	Outward    *TestLeg   `query:"outward"`
+	Inward     *TestLeg   `query:"inward"`
+	Railcards  **string   `query:"railcard"`
+}
+
+func TestMappingNilPointers(t *testing.T) {
+	var r = TestPointerRequest{}
+
+	values, err := url.ParseQuery("adults=0&return_date=2016-12-31T11:00:00Z&outward.station=TBW&railcard=YNG")
+	assert.Nil(t, err)
+
+	err = mapper.Unmarshal(values, &r)
+	assert.Nil(t, err)
+
+	rtnDate, err := time.Parse(time.RFC3339, "2016-12-31T11:00:00Z")
+	assert.Nil(t, err)
+
+	if assert.NotNil(t, r.Adults) {
+		assert.Equal(t, 0, *r.Adults)
+	}
+	assert.Nil(t, r.Children)
+	if assert.NotNil(t, r.ReturnDate) {
+		assert.Equal(t, rtnDate, *r.ReturnDate)
+	}
+	if assert.NotNil(t, r.Outward) {
+		assert.Equal(t, "TBW", r.Outward.Station)
+	}
+	assert.Nil(t, r.Inward)
+	if assert.NotNil(t, r.Railcards) && assert.NotNil(t, *r.Railcards) {
+		assert.Equal(t, "YNG", **r.Railcards)
+	}
+}
+
+type TestBoolRequest struct {
+	Flexible   bool   `query:"flexible"`
+	Direct     bool   `query:"direct"`
+	FirstOnly  *bool  `query:"first_only"`
+	Newsletter bool   `query:"newsletter,checkbox"`
+	Consent    *bool  `query:"consent,checkbox"`
+	Options    []bool `query:"option"`
+}
+
+func TestMappingBools(t *testing.T) {
+	var r = TestBoolRequest{}
+
+	values, err := url.ParseQuery("flexible=TRUE&direct=off&first_only=no&newsletter&consent=&option=yes&option=0&option=On")
+	assert.Nil(t, err)
+
+	err = mapper.Unmarshal(values, &r)
+	assert.Nil(t, err)
+
+	assert.True(t, r.Flexible)
+	assert.False(t, r.Direct)
+	if assert.NotNil(t, r.FirstOnly) {
+		assert.False(t, *r.FirstOnly)
+	}
+	assert.True(t, r.Newsletter)
+	if assert.NotNil(t, r.Consent) {
+		assert.True(t, *r.Consent)
+	}
+	assert.Equal(t, []bool{true, false, true}, r.Options)
+}
+
+func TestIncorrectBool(t *testing.T) {
+	var r = TestBoolRequest{}
+
+	values, err := url.ParseQuery("flexible=maybe")
+	assert.Nil(t, err)
+
+	err = mapper.Unmarshal(values, &r)
+	assert.EqualError(t, err, "Provided value `maybe` for field `Flexible` is not a boolean")
+
+	values, err = url.ParseQuery("direct=")
+	assert.Nil(t, err)
+
+	err = mapper.Unmarshal(values, &r)
+	assert.Nil(t, err)
+	assert.False(t, r.Direct)
+}
+
+type TestDelimitedRequest struct {
+	Stations []string    `query:"stations,sep=,"`
+	Ages     []int       `query:"ages,sep=space"`
+	Classes  []string    `query:"classes,sep=pipe"`
+	Box      [4]float64  `query:"bbox,sep=comma"`
+	Dates    []time.Time `query:"dates,sep=,,layout=DateOnly"`
+}
+
+func TestMappingDelimitedLists(t *testing.T) {
+	var r = TestDelimitedRequest{}
+
+	values, err := url.ParseQuery("stations=TBW, LBG,&stations=VIC&ages=30%2040+4&classes=std|first&bbox=1,2,3,4&dates=2016-12-30,2016-12-31")
+	assert.Nil(t, err)
+
+	err = mapper.Unmarshal(values, &r)
+	assert.Nil(t, err)
+
+	assert.Equal(t, []string{"TBW", "LBG", "VIC"}, r.Stations)
+	assert.Equal(t, []int{30, 40, 4}, r.Ages)
+	assert.Equal(t, []string{"std", "first"}, r.Classes)
+	assert.Equal(t, [4]float64{1, 2, 3, 4}, r.Box)
+	assert.Len(t, r.Dates, 2)
+
+	values, err = url.ParseQuery("ages=30+X")
+	assert.Nil(t, err)
+
+	err = mapper.Unmarshal(values, &r)
+	assert.EqualError(t, err, "Provided value `X` for field `Ages[1]` is not an integer")
+}
+
+type TestIntRangeRequest struct {
+	Pax     int8   `query:"pax"`
+	Small   uint8  `query:"small"`
+	Big     uint64 `query:"big"`
+	Flags   int32  `query:"flags,base"`
+	Colour  uint32 `query:"colour,base=16"`
+	Default int    `query:"default"`
+}
+
+func TestMappingIntRanges(t *testing.T) {
+	var r = TestIntRangeRequest{}
+
+	values, err := url.ParseQuery("pax=-128&small=255&big=18446744073709551615&flags=0b1_0001&colour=ff00ff&default=012")
+	assert.Nil(t, err)
+
+	err = mapper.Unmarshal(values, &r)
+	assert.Nil(t, err)
+
+	assert.Equal(t, int8(-128), r.Pax)
+	assert.Equal(t, uint8(255), r.Small)
+	assert.Equal(t, uint64(18446744073709551615), r.Big)
+	assert.Equal(t, int32(17), r.Flags)
+	assert.Equal(t, uint32(0xff00ff), r.Colour)
+	assert.Equal(t, 12, r.Default)
+
+	for flags, expected := range map[string]int32{"0x1F": 31, "0o17": 15, "1_000": 1000, "-0x10": -16, "010": 10, "-0_10": -10, "00": 0} {
+		err = mapper.Unmarshal(url.Values{"flags": {flags}}, &r)
+		assert.Nil(t, err, flags)
+		assert.Equal(t, expected, r.Flags, flags)
+	}
+
+	r.Flags = -16
+	out, err := mapper.Marshal(r)
+	assert.Nil(t, err)
+	assert.Equal(t, "ff00ff", out.Get("colour"))
+	assert.Equal(t, "-16", out.Get("flags"))
+}
+
+func TestIncorrectIntRanges(t *testing.T) {
+	var r = TestIntRangeRequest{}
+
+	errors := map[string]string{
+		"pax=300":                   "Provided value `300` for field `Pax` is out of range [-128, 127]",
+		"small=256":                 "Provided value `256` for field `Small` is out of range [0, 255]",
+		"small=-1":                  "Negative value `-1` for field `Small` is not supported",
+		"big=18446744073709551616":  "Provided value `18446744073709551616` for field `Big` is out of range [0, 18446744073709551615]",
+		"default=0x10":              "Provided value `0x10` for field `Default` is not an integer",
+		"default=1_000":             "Provided value `1_000` for field `Default` is not an integer",
+		"colour=0xff":               "Provided value `0xff` for field `Colour` is not an integer",
+		"flags=0b102":               "Provided value `0b102` for field `Flags` is not an integer",
+		"pax=-99999999999999999999": "Provided value `-99999999999999999999` for field `Pax` is out of range [-128, 127]",
+	}
+	for query, expected := range errors {
+		values, err := url.ParseQuery(query)
+		assert.Nil(t, err)
+
+		err = mapper.Unmarshal(values, &r)
+		assert.EqualError(t, err, expected, query)
+	}
+
+	var bad struct {
+		ID int `query:"id,base=99"`
+	}
+	err := mapper.Unmarshal(url.Values{"id": {"1"}}, &bad)
+	assert.EqualError(t, err, "Unknown base `99` for field `ID`")
+}
+
+type TestMoneyRequest struct {
+	Amount   int64  `query:"amount,money"`
+	Discount int32  `query:"discount,money,scale=3"`
+	Fee      uint16 `query:"fee,money"`
+	Yen      int    `query:"yen,money,scale=0"`
+}
+
+func TestMappingMoney(t *testing.T) {
+	var r = TestMoneyRequest{}
+
+	values, err := url.ParseQuery("amount=12.50&discount=-0.125&fee=3&yen=1200")
+	assert.Nil(t, err)
+
+	err = mapper.Unmarshal(values, &r)
+	assert.Nil(t, err)
+
+	assert.Equal(t, int64(1250), r.Amount)
+	assert.Equal(t, int32(-125), r.Discount)
+	assert.Equal(t, uint16(300), r.Fee)
+	assert.Equal(t, 1200, r.Yen)
+
+	for amount, expected := range map[string]int64{"0.07": 7, "+1.5": 150, "-19.99": -1999, "0010.10": 1010} {
+		err = mapper.Unmarshal(url.Values{"amount": {amount}}, &r)
+		assert.Nil(t, err, amount)
+		assert.Equal(t, expected, r.Amount, amount)
+	}
+}
+
+func TestIncorrectMoney(t *testing.T) {
+	var r = TestMoneyRequest{}
+
+	errors := map[string]string{
+		"amount=12.505":    "Provided value `12.505` for field `Amount` has more than 2 decimal places",
+		"amount=12.":       "Provided value `12.` for field `Amount` is not a decimal amount",
+		"amount=.5":        "Provided value `.5` for field `Amount` is not a decimal amount",
+		"amount=1e3":       "Provided value `1e3` for field `Amount` is not a decimal amount",
+		"amount=1,50":      "Provided value `1,50` for field `Amount` is not a decimal amount",
+		"yen=1.5":          "Provided value `1.5` for field `Yen` has more than 0 decimal places",
+		"fee=655.36":       "Provided value `655.36` for field `Fee` is out of range [0, 65535]",
+		"fee=-1.00":        "Negative value `-1.00` for field `Fee` is not supported",
+		"discount=2147484": "Provided value `2147484` for field `Discount` is out of range [-2147483648, 2147483647]",
+	}
+	for query, expected := range errors {
+		values, err := url.ParseQuery(query)
+		assert.Nil(t, err)
+
+		err = mapper.Unmarshal(values, &r)
+		assert.EqualError(t, err, expected, query)
+	}
+
+	var bad struct {
+		Amount int `query:"amount,money,scale=19"`
+	}
+	err := mapper.Unmarshal(url.Values{"amount": {"1"}}, &bad)
+	assert.EqualError(t, err, "Unknown scale `19` for field `Amount`")
+}
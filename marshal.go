@@ -0,0 +1,315 @@
+package mapper
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	errWrongMarshalType = errors.New("Marshal only works with structs or pointers to structs")
+	noTimeFormat        = "No time format was provided for field `%s`"
+	wrongCustomValue    = "Value of field `%s` could not be encoded: %s"
+)
+
+// Encoder maps structs onto url.Values. The zero value is ready to use and
+// falls back to DefaultSeparator and DefaultMaxDepth.
+type Encoder struct {
+	// Separator is placed between the key of a struct field and the keys
+	// of its nested fields.
+	Separator string
+
+	// MaxDepth limits how many levels of nested structs are encoded.
+	MaxDepth int
+}
+
+var defaultEncoder = &Encoder{}
+
+// Marshal encodes a struct into url.Values using the same `query` tags and
+// options understood by Unmarshal.
+func Marshal(v interface{}) (url.Values, error) {
+	return defaultEncoder.Marshal(v)
+}
+
+// Marshal encodes a struct into url.Values.
+func (e *Encoder) Marshal(v interface{}) (url.Values, error) {
+	val := reflect.ValueOf(v)
+	for val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			return nil, errWrongMarshalType
+		}
+		val = val.Elem()
+	}
+
+	if val.Kind() != reflect.Struct {
+		return nil, errWrongMarshalType
+	}
+
+	values := make(url.Values)
+	if err := e.structToMap(val, values, "", "", 0); err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
+
+func (e *Encoder) separator() string {
+	if e.Separator == "" {
+		return DefaultSeparator
+	}
+	return e.Separator
+}
+
+func (e *Encoder) maxDepth() int {
+	if e.MaxDepth <= 0 {
+		return DefaultMaxDepth
+	}
+	return e.MaxDepth
+}
+
+// structToMap adds the fields of the struct v to values. The prefix is the
+// query key of the struct itself and path is its Go field path, both empty at
+// the top level.
+func (e *Encoder) structToMap(v reflect.Value, values url.Values, prefix, path string, depth int) error {
+	for _, f := range cachedTypeFields(v.Type()) {
+		mapFromValue := fieldByIndex(v, f.index, false)
+		if !mapFromValue.IsValid() {
+			continue
+		}
+
+		key := joinKey(prefix, e.separator(), f.name)
+		fieldName := f.path
+		if path != "" {
+			fieldName = path + "." + fieldName
+		}
+
+		if f.opts.Contains("omitempty") && isEmptyValue(mapFromValue) {
+			continue
+		}
+
+		if err := e.encodeField(mapFromValue, values, key, fieldName, f.opts, depth); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// encodeField adds v to values under key, recursing into nested structs,
+// slices of structs and maps.
+func (e *Encoder) encodeField(v reflect.Value, values url.Values, key, fieldName string, opts TagOptions, depth int) error {
+	for v.Kind() == reflect.Ptr {
+		if _, ok := encoderFor(v.Type()); ok {
+			break
+		}
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	_, registered := encoderFor(v.Type())
+
+	if m, ok := queryMarshaler(v); ok && !registered {
+		vals, err := m.MarshalQuery()
+		if err != nil {
+			return errors.New(fmt.Sprintf(wrongCustomValue, fieldName, err))
+		}
+		for _, value := range vals {
+			values.Add(key, value)
+		}
+		return nil
+	}
+
+	if isNestedType(v.Type()) {
+		if depth >= e.maxDepth() {
+			return errors.New(fmt.Sprintf(maxDepthExceeded, fieldName, e.maxDepth()))
+		}
+
+		switch v.Kind() {
+		case reflect.Struct:
+			return e.structToMap(v, values, key, fieldName, depth+1)
+		case reflect.Slice:
+			return e.structSliceToMap(v, values, key, fieldName, depth+1)
+		case reflect.Map:
+			return e.mapToValues(v, values, key, fieldName, opts, depth+1)
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		var elems []string
+		for i := 0; i < v.Len(); i++ {
+			value, ok, err := encodeValue(v.Index(i), fmt.Sprintf("%s[%d]", fieldName, i), opts)
+			if err != nil {
+				return err
+			}
+			if ok {
+				elems = append(elems, value)
+			}
+		}
+
+		// Delimited lists are written as a single value.
+		if sep := separatorFromOptions(opts); sep != "" {
+			if len(elems) > 0 {
+				values.Add(key, strings.Join(elems, sep))
+			}
+			return nil
+		}
+
+		for _, value := range elems {
+			values.Add(key, value)
+		}
+		return nil
+	}
+
+	value, ok, err := encodeValue(v, fieldName, opts)
+	if err != nil {
+		return err
+	}
+	if ok {
+		values.Add(key, value)
+	}
+
+	return nil
+}
+
+// structSliceToMap adds every struct in v under an indexed key such as
+// `passengers.0.age`, the form read back by Unmarshal.
+func (e *Encoder) structSliceToMap(v reflect.Value, values url.Values, prefix, path string, depth int) error {
+	for i := 0; i < v.Len(); i++ {
+		elem := v.Index(i)
+		for elem.Kind() == reflect.Ptr && !elem.IsNil() {
+			elem = elem.Elem()
+		}
+		if elem.Kind() == reflect.Ptr {
+			continue
+		}
+
+		elemKey := joinKey(prefix, e.separator(), strconv.Itoa(i))
+		elemPath := fmt.Sprintf("%s[%d]", path, i)
+		if err := e.structToMap(elem, values, elemKey, elemPath, depth); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// mapToValues adds every entry of the map v under the key prefix plus
+// separator plus map key, such as `meta.channel`.
+func (e *Encoder) mapToValues(v reflect.Value, values url.Values, prefix, path string, opts TagOptions, depth int) error {
+	for _, mapKey := range v.MapKeys() {
+		entry, ok, err := encodeValue(mapKey, path, nil)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+
+		entryKey := joinKey(prefix, e.separator(), entry)
+		entryName := fmt.Sprintf("%s[%s]", path, entry)
+		if err := e.encodeField(v.MapIndex(mapKey), values, entryKey, entryName, opts, depth); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// encodeValue converts a single value into its query string representation.
+// The boolean result is false for nil pointers and for kinds that Unmarshal
+// does not support.
+func encodeValue(v reflect.Value, fieldName string, opts TagOptions) (string, bool, error) {
+	for {
+		if fn, ok := encoderFor(v.Type()); ok {
+			value, err := fn(v.Interface())
+			if err != nil {
+				return "", false, errors.New(fmt.Sprintf(wrongCustomValue, fieldName, err))
+			}
+			return value, true, nil
+		}
+
+		if v.Kind() != reflect.Ptr {
+			break
+		}
+		if v.IsNil() {
+			return "", false, nil
+		}
+		v = v.Elem()
+	}
+
+	if value, ok, err := formatBig(v, fieldName, opts); ok || err != nil {
+		return value, ok, err
+	}
+
+	if m, ok := textMarshaler(v); ok {
+		text, err := m.MarshalText()
+		if err != nil {
+			return "", false, errors.New(fmt.Sprintf(wrongCustomValue, fieldName, err))
+		}
+		return string(text), true, nil
+	}
+
+	if v.Type() == timeType {
+		value, ok, err := formatTime(v.Interface().(time.Time), fieldName, opts)
+		if err != nil || ok {
+			return value, ok, err
+		}
+
+		return "", false, errors.New(fmt.Sprintf(noTimeFormat, fieldName))
+	}
+
+	if v.Type() == durationType {
+		return formatDuration(time.Duration(v.Int()), opts), true, nil
+	}
+
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if opts.Contains("money") {
+			scale, err := moneyScale(fieldName, opts)
+			return formatMinorUnits(strconv.FormatInt(v.Int(), 10), scale), err == nil, err
+		}
+		base, err := intBase(fieldName, opts)
+		if err != nil {
+			return "", false, err
+		}
+		if base == 0 {
+			base = 10
+		}
+		return strconv.FormatInt(v.Int(), base), true, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if opts.Contains("money") {
+			scale, err := moneyScale(fieldName, opts)
+			return formatMinorUnits(strconv.FormatUint(v.Uint(), 10), scale), err == nil, err
+		}
+		base, err := intBase(fieldName, opts)
+		if err != nil {
+			return "", false, err
+		}
+		if base == 0 {
+			base = 10
+		}
+		return strconv.FormatUint(v.Uint(), base), true, nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits()), true, nil
+	case reflect.Bool:
+		if opts.Contains("checkbox") {
+			// An unticked checkbox is not sent at all.
+			return "on", v.Bool(), nil
+		}
+		if v.Bool() {
+			return "1", true, nil
+		}
+		return "0", true, nil
+	case reflect.String:
+		return v.String(), true, nil
+	}
+
+	return "", false, nil
+}
@@ -0,0 +1,207 @@
+package mapper_test
+
+import (
+	"github.com/assertis/url-mapper"
+	"github.com/stretchr/testify/assert"
+	"net/url"
+	"testing"
+	"time"
+)
+
+type TestMarshalRequest struct {
+	Origin      string    `query:"o"`
+	Destination string    `query:"d,omitempty"`
+	Adults      uint      `query:"adults"`
+	Children    *int      `query:"children"`
+	Flexible    bool      `query:"flexible"`
+	Via         []string  `query:"via"`
+	OutwardDate time.Time `query:"outward_date,unix"`
+	ReturnDate  time.Time `query:"return_date,rfc3339,omitempty"`
+	Internal    string    `query:"-"`
+	Untagged    string
+}
+
+type TestRoundTripRequest struct {
+	Origin      string    `query:"o"`
+	Adults      uint      `query:"adults"`
+	Children    *int      `query:"children,omitempty"`
+	Flexible    bool      `query:"flexible"`
+	Via         []string  `query:"via"`
+	OutwardDate time.Time `query:"outward_date,unix"`
+	ReturnDate  time.Time `query:"return_date,rfc3339,omitempty"`
+}
+
+func TestMarshal(t *testing.T) {
+	children := 2
+	r := TestMarshalRequest{
+		Origin:      "TBW",
+		Adults:      1,
+		Children:    &children,
+		Flexible:    true,
+		Via:         []string{"LBG", "VIC"},
+		OutwardDate: time.Unix(1482852746, 0),
+		Internal:    "secret",
+		Untagged:    "ignored",
+	}
+
+	values, err := mapper.Marshal(&r)
+	assert.Nil(t, err)
+
+	expected := url.Values{
+		"o":            {"TBW"},
+		"adults":       {"1"},
+		"children":     {"2"},
+		"flexible":     {"1"},
+		"via":          {"LBG", "VIC"},
+		"outward_date": {"1482852746"},
+	}
+	assert.Equal(t, expected, values)
+}
+
+func TestMarshalRoundTrip(t *testing.T) {
+	rtnDate, err := time.Parse(time.RFC3339, "2016-12-31T11:00:00Z")
+	assert.Nil(t, err)
+
+	children := 0
+	in := TestRoundTripRequest{
+		Origin:      "TBW",
+		Adults:      2,
+		Children:    &children,
+		Flexible:    true,
+		Via:         []string{"ECR"},
+		OutwardDate: time.Unix(1482852746, 0),
+		ReturnDate:  rtnDate,
+	}
+
+	values, err := mapper.Marshal(in)
+	assert.Nil(t, err)
+
+	var out TestRoundTripRequest
+	err = mapper.Unmarshal(values, &out)
+	assert.Nil(t, err)
+	assert.Equal(t, in, out)
+
+	// Empty omitempty fields are left out, and stay empty when decoded.
+	in.Children, in.ReturnDate = nil, time.Time{}
+	values, err = mapper.Marshal(in)
+	assert.Nil(t, err)
+	assert.NotContains(t, values, "children")
+	assert.NotContains(t, values, "return_date")
+
+	out = TestRoundTripRequest{}
+	err = mapper.Unmarshal(values, &out)
+	assert.Nil(t, err)
+	assert.Equal(t, in, out)
+}
+
+func TestMarshalTimeWithoutFormat(t *testing.T) {
+	r := struct {
+		Date time.Time `query:"date"`
+	}{}
+
+	_, err := mapper.Marshal(r)
+	assert.NotNil(t, err)
+}
+
+func TestMarshalNonStruct(t *testing.T) {
+	_, err := mapper.Marshal("TBW")
+	assert.NotNil(t, err)
+}
+
+func TestMarshalFloats(t *testing.T) {
+	r := struct {
+		Latitude   float64 `query:"lat"`
+		Multiplier float32 `query:"multiplier"`
+	}{51.5074, 0.1}
+
+	values, err := mapper.Marshal(r)
+	assert.Nil(t, err)
+	assert.Equal(t, "51.5074", values.Get("lat"))
+	assert.Equal(t, "0.1", values.Get("multiplier"))
+}
+
+type TestMarshalLeg struct {
+	Station string    `query:"station"`
+	Date    time.Time `query:"date,unix"`
+}
+
+type TestMarshalJourney struct {
+	Outward TestMarshalLeg `query:"outward"`
+}
+
+func TestMarshalNestedStructs(t *testing.T) {
+	r := TestMarshalJourney{TestMarshalLeg{"TBW", time.Unix(1482852746, 0)}}
+
+	values, err := mapper.Marshal(r)
+	assert.Nil(t, err)
+	assert.Equal(t, url.Values{"outward.station": {"TBW"}, "outward.date": {"1482852746"}}, values)
+
+	encoder := mapper.Encoder{Separator: "_"}
+	values, err = encoder.Marshal(r)
+	assert.Nil(t, err)
+	assert.Equal(t, url.Values{"outward_station": {"TBW"}, "outward_date": {"1482852746"}}, values)
+
+	var out TestMarshalJourney
+	decoder := mapper.Decoder{Separator: "_"}
+	err = decoder.Unmarshal(values, &out)
+	assert.Nil(t, err)
+	assert.Equal(t, r, out)
+}
+
+func TestMarshalStructSlices(t *testing.T) {
+	r := struct {
+		Legs []TestMarshalLeg `query:"legs"`
+	}{[]TestMarshalLeg{{Station: "TBW"}, {Station: "LBG"}}}
+
+	values, err := mapper.Marshal(r)
+	assert.Nil(t, err)
+	assert.Equal(t, "TBW", values.Get("legs.0.station"))
+	assert.Equal(t, "LBG", values.Get("legs.1.station"))
+}
+
+func TestMarshalMaps(t *testing.T) {
+	r := struct {
+		Meta   map[string]string `query:"meta"`
+		Counts map[string]int    `query:"count"`
+	}{map[string]string{"channel": "web"}, map[string]int{"adult": 2, "child": 1}}
+
+	values, err := mapper.Marshal(r)
+	assert.Nil(t, err)
+	assert.Equal(t, url.Values{"meta.channel": {"web"}, "count.adult": {"2"}, "count.child": {"1"}}, values)
+}
+
+func TestMarshalCheckbox(t *testing.T) {
+	r := struct {
+		Newsletter bool `query:"newsletter,checkbox"`
+		Consent    bool `query:"consent,checkbox"`
+	}{Newsletter: true}
+
+	values, err := mapper.Marshal(r)
+	assert.Nil(t, err)
+	assert.Equal(t, url.Values{"newsletter": {"on"}}, values)
+}
+
+func TestMarshalDelimitedLists(t *testing.T) {
+	r := struct {
+		Stations []string `query:"stations,sep=,"`
+		Ages     []int    `query:"ages,sep=space"`
+		Classes  []string `query:"classes,sep=pipe"`
+		Empty    []string `query:"empty,sep=,"`
+	}{[]string{"TBW", "LBG"}, []int{30, 4}, []string{"std", "first"}, nil}
+
+	values, err := mapper.Marshal(r)
+	assert.Nil(t, err)
+	assert.Equal(t, url.Values{"stations": {"TBW,LBG"}, "ages": {"30 4"}, "classes": {"std|first"}}, values)
+}
+
+func TestMarshalMoney(t *testing.T) {
+	r := TestMoneyRequest{Amount: 1250, Discount: -5, Fee: 7, Yen: 1200}
+
+	values, err := mapper.Marshal(r)
+	assert.Nil(t, err)
+	assert.Equal(t, url.Values{"amount": {"12.50"}, "discount": {"-0.005"}, "fee": {"0.07"}, "yen": {"1200"}}, values)
+
+	var decoded TestMoneyRequest
+	assert.Nil(t, mapper.Unmarshal(values, &decoded))
+	assert.Equal(t, r, decoded)
+}
@@ -0,0 +1,73 @@
+package mapper
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	wrongMoneyType    = "Provided value `%s` for field `%s` is not a decimal amount"
+	tooManyDecimals   = "Provided value `%s` for field `%s` has more than %d decimal places"
+	unknownMoneyScale = "Unknown scale `%s` for field `%s`"
+
+	decimalAmount = regexp.MustCompile(`^[+-]?[0-9]+(\.[0-9]+)?$`)
+)
+
+// moneyScale returns the number of minor unit digits given in the "scale"
+// option, 2 by default.
+func moneyScale(fieldName string, opts TagOptions) (int, error) {
+	if !opts.Contains("scale") {
+		return 2, nil
+	}
+
+	scale, err := strconv.Atoi(opts["scale"])
+	if err != nil || scale < 0 || scale > 18 {
+		return 0, errors.New(fmt.Sprintf(unknownMoneyScale, opts["scale"], fieldName))
+	}
+	return scale, nil
+}
+
+// minorUnits rewrites a decimal amount such as `12.5` as the integer string
+// `1250` for a scale of 2, without going through floating point. Amounts
+// with more fractional digits than the scale are rejected.
+func minorUnits(value string, fieldName string, opts TagOptions) (string, error) {
+	scale, err := moneyScale(fieldName, opts)
+	if err != nil {
+		return "", err
+	}
+
+	if !decimalAmount.MatchString(value) {
+		return "", errors.New(fmt.Sprintf(wrongMoneyType, value, fieldName))
+	}
+
+	whole, frac := value, ""
+	if i := strings.Index(value, "."); i >= 0 {
+		whole, frac = value[:i], value[i+1:]
+	}
+	if len(frac) > scale {
+		return "", errors.New(fmt.Sprintf(tooManyDecimals, value, fieldName, scale))
+	}
+
+	return whole + frac + strings.Repeat("0", scale-len(frac)), nil
+}
+
+// formatMinorUnits writes the integer string of an amount in minor units
+// back in decimal form, `-1250` becoming `-12.50` for a scale of 2.
+func formatMinorUnits(digits string, scale int) string {
+	if scale == 0 {
+		return digits
+	}
+
+	sign := ""
+	if strings.HasPrefix(digits, "-") {
+		sign, digits = "-", digits[1:]
+	}
+	if len(digits) <= scale {
+		digits = strings.Repeat("0", scale-len(digits)+1) + digits
+	}
+
+	return sign + digits[:len(digits)-scale] + "." + digits[len(digits)-scale:]
+}
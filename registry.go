@@ -0,0 +1,131 @@
+package mapper
+
+import (
+	"errors"
+	"fmt"
+	"github.com/asaskevich/govalidator"
+	"reflect"
+	"sync"
+)
+
+var wrongConverterResult = "Converter for field `%s` returned `%T`, expected `%s`"
+
+var registry = struct {
+	sync.RWMutex
+	converters map[reflect.Type]func(string) (interface{}, error)
+	encoders   map[reflect.Type]func(interface{}) (string, error)
+	validators map[string]func(string) bool
+}{
+	converters: make(map[reflect.Type]func(string) (interface{}, error)),
+	encoders:   make(map[reflect.Type]func(interface{}) (string, error)),
+	// govalidator functions missing from its TagMap.
+	validators: map[string]func(string) bool{
+		"iso3166alpha2": govalidator.IsISO3166Alpha2,
+		"iso3166alpha3": govalidator.IsISO3166Alpha3,
+		"cidr":          govalidator.IsCIDR,
+		"mongoid":       govalidator.IsMongoID,
+	},
+}
+
+// RegisterConverter teaches Unmarshal how to decode a value of type t, for
+// types that cannot implement QueryUnmarshaler themselves. The function
+// receives a single query string value and returns a value assignable or
+// convertible to t. Registered converters take precedence over everything
+// else, including the built-in handling of time.Time.
+//
+// Converters are meant to be registered at init time; lookups are safe for
+// concurrent use.
+func RegisterConverter(t reflect.Type, fn func(string) (interface{}, error)) {
+	registry.Lock()
+	defer registry.Unlock()
+	registry.converters[t] = fn
+}
+
+// RegisterEncoder is the Marshal counterpart of RegisterConverter. The
+// function receives a value of type t and returns its query string form.
+func RegisterEncoder(t reflect.Type, fn func(interface{}) (string, error)) {
+	registry.Lock()
+	defer registry.Unlock()
+	registry.encoders[t] = fn
+}
+
+// RegisterValidator makes fn available to the "validate" tag option under
+// name, e.g. `query:"crs,validate=crs"`. The names of govalidator's TagMap,
+// such as email, uuid or ip, are available without registering them;
+// registering one of them replaces it.
+func RegisterValidator(name string, fn func(string) bool) {
+	registry.Lock()
+	defer registry.Unlock()
+	registry.validators[name] = fn
+}
+
+func converterFor(t reflect.Type) (func(string) (interface{}, error), bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+	fn, ok := registry.converters[t]
+	return fn, ok
+}
+
+func encoderFor(t reflect.Type) (func(interface{}) (string, error), bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+	fn, ok := registry.encoders[t]
+	return fn, ok
+}
+
+// isRegisteredType reports whether a converter or an encoder was registered
+// for t.
+func isRegisteredType(t reflect.Type) bool {
+	registry.RLock()
+	defer registry.RUnlock()
+	_, decode := registry.converters[t]
+	_, encode := registry.encoders[t]
+	return decode || encode
+}
+
+// convert decodes value into v with the converter registered for its type.
+// The boolean result is false when there is none.
+func convert(v reflect.Value, value string, fieldName string) (bool, error) {
+	fn, ok := converterFor(v.Type())
+	if !ok {
+		return false, nil
+	}
+
+	result, err := fn(value)
+	if err != nil {
+		return true, errors.New(fmt.Sprintf(wrongCustomType, value, fieldName, err))
+	}
+
+	if result == nil {
+		v.Set(reflect.Zero(v.Type()))
+		return true, nil
+	}
+
+	rv := reflect.ValueOf(result)
+	switch {
+	case rv.Type().AssignableTo(v.Type()):
+		v.Set(rv)
+	case rv.Type().ConvertibleTo(v.Type()):
+		v.Set(rv.Convert(v.Type()))
+	default:
+		return true, errors.New(fmt.Sprintf(wrongConverterResult, fieldName, result, v.Type()))
+	}
+
+	return true, nil
+}
+
+// validatorFor returns the validator registered under name, falling back to
+// govalidator's TagMap.
+func validatorFor(name string) (func(string) bool, bool) {
+	registry.RLock()
+	fn, ok := registry.validators[name]
+	registry.RUnlock()
+	if ok {
+		return fn, true
+	}
+
+	if fn, ok := govalidator.TagMap[name]; ok {
+		return fn, true
+	}
+	return nil, false
+}
@@ -0,0 +1,100 @@
+package mapper_test
+
+import (
+	"errors"
+	"fmt"
+	"github.com/assertis/url-mapper"
+	"github.com/stretchr/testify/assert"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// TestBookingRef stands in for a third-party type that cannot be given
+// methods.
+type TestBookingRef struct {
+	Prefix string
+	Number int
+}
+
+func init() {
+	mapper.RegisterConverter(reflect.TypeOf(TestBookingRef{}), func(value string) (interface{}, error) {
+		parts := strings.SplitN(value, "-", 2)
+		if len(parts) != 2 {
+			return nil, errors.New("missing dash")
+		}
+		n, err := strconv.Atoi(parts[1])
+		if err != nil {
+			return nil, err
+		}
+		return TestBookingRef{parts[0], n}, nil
+	})
+	mapper.RegisterEncoder(reflect.TypeOf(TestBookingRef{}), func(v interface{}) (string, error) {
+		ref := v.(TestBookingRef)
+		return fmt.Sprintf("%s-%d", ref.Prefix, ref.Number), nil
+	})
+
+	mapper.RegisterConverter(reflect.TypeOf(&url.URL{}), func(value string) (interface{}, error) {
+		return url.Parse(value)
+	})
+	mapper.RegisterEncoder(reflect.TypeOf(&url.URL{}), func(v interface{}) (string, error) {
+		return v.(*url.URL).String(), nil
+	})
+}
+
+type TestRegistryRequest struct {
+	Booking  TestBookingRef   `query:"booking"`
+	Previous []TestBookingRef `query:"previous"`
+	Callback *url.URL         `query:"callback"`
+}
+
+func TestMappingRegisteredConverters(t *testing.T) {
+	var r = TestRegistryRequest{}
+
+	values, err := url.ParseQuery("booking=ABC-12&previous=XY-1&previous=XY-2&callback=https%3A%2F%2Fexample.com%2Fdone")
+	assert.Nil(t, err)
+
+	err = mapper.Unmarshal(values, &r)
+	assert.Nil(t, err)
+
+	assert.Equal(t, TestBookingRef{"ABC", 12}, r.Booking)
+	assert.Equal(t, []TestBookingRef{{"XY", 1}, {"XY", 2}}, r.Previous)
+	if assert.NotNil(t, r.Callback) {
+		assert.Equal(t, "example.com", r.Callback.Host)
+	}
+
+	out, err := mapper.Marshal(r)
+	assert.Nil(t, err)
+	assert.Equal(t, values, out)
+}
+
+func TestIncorrectRegisteredConverterValue(t *testing.T) {
+	var r = TestRegistryRequest{}
+
+	values, err := url.ParseQuery("previous=XY-1&previous=XY2")
+	assert.Nil(t, err)
+
+	err = mapper.Unmarshal(values, &r)
+	assert.EqualError(t, err, "Provided value `XY2` for field `Previous[1]` is not valid: missing dash")
+}
+
+func TestRegisterValidator(t *testing.T) {
+	mapper.RegisterValidator("crs", func(value string) bool {
+		return len(value) == 3 && strings.ToUpper(value) == value
+	})
+
+	var r struct {
+		Stations []string `query:"stations,sep=,,validate=alpha|crs"`
+	}
+
+	err := mapper.Unmarshal(url.Values{"stations": {"TBW,LBG"}}, &r)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"TBW", "LBG"}, r.Stations)
+
+	err = mapper.Unmarshal(url.Values{"stations": {"TBW,lbg,L1"}}, &r)
+	assert.EqualError(t, err, "Provided value `lbg` for field `Stations[1]` is not a valid crs; "+
+		"Provided value `L1` for field `Stations[2]` is not a valid alpha; "+
+		"Provided value `L1` for field `Stations[2]` is not a valid crs")
+}
@@ -13,18 +13,26 @@ func TagOptionsFromString(tag string) (string, TagOptions) {
 	tagMap := make(TagOptions)
 	name := ""
 
-	options := strings.SplitN(tag, ",", -1)
-	for i, option := range options {
+	options := splitOptions(tag)
+	for i := 0; i < len(options); i++ {
+		option := options[i]
 		if i == 0 {
 			name = option
 			continue
 		}
 
-		validationOptions := strings.Split(option, "=")
+		validationOptions := strings.SplitN(option, "=", 2)
 		if !isValidTag(validationOptions[0]) {
 			continue
 		}
 
+		// An option followed by an empty one, as in `sep=,`, has a
+		// comma for its value.
+		if len(validationOptions) == 2 && validationOptions[1] == "" && i+1 < len(options) && options[i+1] == "" {
+			validationOptions[1] = ","
+			i++
+		}
+
 		if len(validationOptions) == 2 {
 			tagMap[validationOptions[0]] = validationOptions[1]
 		} else {
@@ -34,6 +42,41 @@ func TagOptionsFromString(tag string) (string, TagOptions) {
 	return name, tagMap
 }
 
+// splitOptions splits a tag at its commas, except for commas escaped as `\,`
+// and commas within braces, so that `pattern=^[A-Z]{2,3}$` stays a single
+// option.
+func splitOptions(tag string) []string {
+	var options []string
+	var option strings.Builder
+
+	depth := 0
+	for i := 0; i < len(tag); i++ {
+		c := tag[i]
+		switch {
+		case c == '\\' && i+1 < len(tag):
+			// Only the escaped comma loses its backslash; `\d` and
+			// the like are left to the pattern.
+			i++
+			if tag[i] != ',' {
+				option.WriteByte(c)
+			}
+			option.WriteByte(tag[i])
+			continue
+		case c == '{':
+			depth++
+		case c == '}' && depth > 0:
+			depth--
+		case c == ',' && depth == 0:
+			options = append(options, option.String())
+			option.Reset()
+			continue
+		}
+		option.WriteByte(c)
+	}
+
+	return append(options, option.String())
+}
+
 func (tag TagOptions) Contains(tagName string) bool {
 	if _, ok := tag[tagName]; ok {
 		return true
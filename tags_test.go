@@ -28,3 +28,31 @@ func TestParseTags(t *testing.T) {
 	assert.Equal(t, "ab", name)
 	assert.Equal(t, expected, opts)
 }
+
+func TestParseTagsCommaValue(t *testing.T) {
+	expected := mapper.TagOptions{"sep": ",", "omitempty": ""}
+	name, opts := mapper.TagOptionsFromString("stations,sep=,,omitempty")
+	assert.Equal(t, "stations", name)
+	assert.Equal(t, expected, opts)
+
+	expected = mapper.TagOptions{"sep": ","}
+	name, opts = mapper.TagOptionsFromString("stations,sep=,")
+	assert.Equal(t, "stations", name)
+	assert.Equal(t, expected, opts)
+}
+
+func TestParseTagsWithEqualsInValue(t *testing.T) {
+	name, opts := mapper.TagOptionsFromString("code,pattern=^a=b$")
+	assert.Equal(t, "code", name)
+	assert.Equal(t, mapper.TagOptions{"pattern": "^a=b$"}, opts)
+}
+
+func TestParseTagsWithCommaInPattern(t *testing.T) {
+	name, opts := mapper.TagOptionsFromString("code,pattern=^[A-Z]{2,3}$,required")
+	assert.Equal(t, "code", name)
+	assert.Equal(t, mapper.TagOptions{"pattern": "^[A-Z]{2,3}$", "required": ""}, opts)
+
+	name, opts = mapper.TagOptionsFromString(`code,pattern=^a\,b\d$,required`)
+	assert.Equal(t, "code", name)
+	assert.Equal(t, mapper.TagOptions{"pattern": `^a,b\d$`, "required": ""}, opts)
+}
@@ -0,0 +1,396 @@
+package mapper
+
+import (
+	"errors"
+	"fmt"
+	"github.com/asaskevich/govalidator"
+	"math"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	durationType = reflect.TypeOf(time.Duration(0))
+
+	// Layouts that can be named in the "layout" and "formats" options.
+	timeLayouts = map[string]string{
+		"ansic":       time.ANSIC,
+		"unixdate":    time.UnixDate,
+		"rubydate":    time.RubyDate,
+		"rfc822":      time.RFC822,
+		"rfc822z":     time.RFC822Z,
+		"rfc850":      time.RFC850,
+		"rfc1123":     time.RFC1123,
+		"rfc1123z":    time.RFC1123Z,
+		"rfc3339":     time.RFC3339,
+		"rfc3339nano": time.RFC3339Nano,
+		"kitchen":     time.Kitchen,
+		"stamp":       time.Stamp,
+		"stampmilli":  time.StampMilli,
+		"stampmicro":  time.StampMicro,
+		"stampnano":   time.StampNano,
+		"datetime":    time.DateTime,
+		"dateonly":    time.DateOnly,
+		"timeonly":    time.TimeOnly,
+	}
+
+	timeOutOfRange      = "Provided value `%s` for field `%s` is out of the supported time range"
+	timeNearEpoch       = "Provided value `%s` for field `%s` is too close to the Unix epoch, it may be in the wrong unit"
+	unknownTimeZone     = "Unknown time zone `%s` for field `%s`"
+	wrongDurationType   = "Provided value `%s` for field `%s` is not a duration"
+	unknownDurationUnit = "Unknown duration unit `%s` for field `%s`"
+
+	// ISO 8601 durations limited to fixed-length units, e.g. P1DT2H30M.
+	iso8601Duration = regexp.MustCompile(`^(-)?P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+(?:\.\d+)?)S)?)?$`)
+)
+
+// parseTime reads a time.Time using the formats given in the tag options,
+// tried in this order:
+//
+//	rfc3339                     RFC 3339
+//	unix                        seconds since the Unix epoch
+//	unixms, unixus, unixns      milli, micro or nanoseconds since the Unix epoch
+//	unixfrac                    seconds since the Unix epoch with a fraction, e.g. 1482852746.25
+//	layout=2006-01-02           a layout, or the name of one of the time package constants
+//	formats=DateOnly|02/01/2006 several layouts or names, separated by `|`
+//
+// Unix timestamps outside the years 1 to 9999 are rejected, which catches
+// timestamps sent in a smaller unit than expected. Timestamps sent in a larger
+// unit, such as seconds to "unixms", end up close to the epoch instead, so
+// timestamps in milli, micro or nanoseconds within a year of it are rejected
+// too. The result is in the location named by the "tz" option, or else the
+// Decoder's Location, which is also used for layouts without a zone offset.
+// The "utc" option converts the result to UTC.
+func (d *Decoder) parseTime(value string, fieldName string, opts TagOptions) (time.Time, error) {
+	loc, err := d.location(fieldName, opts)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	t, unit, ok := parseTimeIn(value, opts, loc)
+	if !ok {
+		return time.Time{}, errors.New(fmt.Sprintf(wrongTimeType, value, fieldName))
+	}
+	if unit != 0 && (t.UTC().Year() < 1 || t.UTC().Year() > 9999) {
+		return time.Time{}, errors.New(fmt.Sprintf(timeOutOfRange, value, fieldName))
+	}
+	if unit != 0 && unit < time.Second && t.After(unixEpoch.Add(-epochMargin)) && t.Before(unixEpoch.Add(epochMargin)) {
+		return time.Time{}, errors.New(fmt.Sprintf(timeNearEpoch, value, fieldName))
+	}
+
+	if loc != nil {
+		t = t.In(loc)
+	}
+	if opts.Contains("utc") {
+		t = t.UTC()
+	}
+	return t, nil
+}
+
+// parseTimeIn does the parsing for parseTime. It also returns the unit of
+// the value if it was read as a Unix timestamp, or else 0.
+func parseTimeIn(value string, opts TagOptions, loc *time.Location) (time.Time, time.Duration, bool) {
+	if opts.Contains("rfc3339") && govalidator.IsRFC3339(value) {
+		t, err := time.Parse(time.RFC3339, value)
+		return t, 0, err == nil
+	}
+
+	for _, u := range unixUnits {
+		if !opts.Contains(u.option) {
+			continue
+		}
+		if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+			perSecond := int64(time.Second / u.unit)
+			return time.Unix(i/perSecond, i%perSecond*int64(u.unit)), u.unit, true
+		}
+	}
+
+	if opts.Contains("unixfrac") {
+		if t, ok := parseFractionalUnix(value); ok {
+			return t, time.Second, true
+		}
+	}
+
+	if loc == nil {
+		loc = time.UTC
+	}
+	for _, layout := range timeLayoutsFromOptions(opts) {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, 0, true
+		}
+	}
+
+	return time.Time{}, 0, false
+}
+
+var unixEpoch = time.Unix(0, 0)
+
+// epochMargin is the distance from the epoch within which timestamps in
+// milli, micro or nanoseconds are rejected. A timestamp read in the next
+// smaller unit ends up 1000 times closer to the epoch, so this catches those
+// of times up to a thousand years away.
+const epochMargin = 365 * 24 * time.Hour
+
+// unixUnits are the options for integer Unix timestamps and their unit.
+var unixUnits = []struct {
+	option string
+	unit   time.Duration
+}{
+	{"unix", time.Second},
+	{"unixms", time.Millisecond},
+	{"unixus", time.Microsecond},
+	{"unixns", time.Nanosecond},
+}
+
+// parseFractionalUnix reads seconds since the Unix epoch with an optional
+// fraction of up to nine digits, without going through float64.
+func parseFractionalUnix(value string) (time.Time, bool) {
+	whole, frac := value, ""
+	if i := strings.Index(value, "."); i >= 0 {
+		whole, frac = value[:i], value[i+1:]
+	}
+	if len(frac) > 9 || strings.Trim(frac, "0123456789") != "" {
+		return time.Time{}, false
+	}
+
+	negative := strings.HasPrefix(whole, "-")
+	sec, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	var nsec int64
+	if frac != "" {
+		nsec, _ = strconv.ParseInt(frac+strings.Repeat("0", 9-len(frac)), 10, 64)
+	}
+	if negative {
+		nsec = -nsec
+	}
+
+	return time.Unix(sec, nsec), true
+}
+
+// location returns the location named by the "tz" option, falling back to
+// the Decoder's Location. It is nil when neither is set.
+func (d *Decoder) location(fieldName string, opts TagOptions) (*time.Location, error) {
+	if opts.Contains("tz") {
+		return loadLocation(opts["tz"], fieldName)
+	}
+	return d.Location, nil
+}
+
+var locationCache sync.Map // map[string]*time.Location
+
+func loadLocation(name string, fieldName string) (*time.Location, error) {
+	if loc, ok := locationCache.Load(name); ok {
+		return loc.(*time.Location), nil
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil || name == "" {
+		return nil, errors.New(fmt.Sprintf(unknownTimeZone, name, fieldName))
+	}
+
+	locationCache.Store(name, loc)
+	return loc, nil
+}
+
+// timeLayoutsFromOptions returns the layouts given in the "layout" and
+// "formats" options, with named presets resolved.
+func timeLayoutsFromOptions(opts TagOptions) []string {
+	var names []string
+	if opts.Contains("layout") {
+		names = append(names, opts["layout"])
+	}
+	if opts.Contains("formats") {
+		names = append(names, strings.Split(opts["formats"], "|")...)
+	}
+
+	layouts := make([]string, 0, len(names))
+	for _, name := range names {
+		if name == "" {
+			continue
+		}
+		if layout, ok := timeLayouts[strings.ToLower(name)]; ok {
+			name = layout
+		}
+		layouts = append(layouts, name)
+	}
+	return layouts
+}
+
+// formatTime is the reverse of parseTime, using the first format given. The
+// time is converted to the location named by the "tz" option first, or to
+// UTC with the "utc" option.
+func formatTime(t time.Time, fieldName string, opts TagOptions) (string, bool, error) {
+	if opts.Contains("tz") {
+		loc, err := loadLocation(opts["tz"], fieldName)
+		if err != nil {
+			return "", false, err
+		}
+		t = t.In(loc)
+	}
+	if opts.Contains("utc") {
+		t = t.UTC()
+	}
+
+	if opts.Contains("rfc3339") {
+		return t.Format(time.RFC3339), true, nil
+	}
+	for _, u := range unixUnits {
+		if opts.Contains(u.option) {
+			perSecond := int64(time.Second / u.unit)
+			return strconv.FormatInt(t.Unix()*perSecond+int64(t.Nanosecond())/int64(u.unit), 10), true, nil
+		}
+	}
+	if opts.Contains("unixfrac") {
+		return formatFractionalUnix(t), true, nil
+	}
+	if layouts := timeLayoutsFromOptions(opts); len(layouts) > 0 {
+		return t.Format(layouts[0]), true, nil
+	}
+	return "", false, nil
+}
+
+func formatFractionalUnix(t time.Time) string {
+	sec, nsec := t.Unix(), int64(t.Nanosecond())
+	if nsec == 0 {
+		return strconv.FormatInt(sec, 10)
+	}
+
+	sign := ""
+	if sec < 0 {
+		// Nanoseconds are always positive, carry them into the seconds.
+		sec, nsec = sec+1, int64(time.Second)-nsec
+		if sec == 0 {
+			sign = "-"
+		}
+	}
+
+	frac := strings.TrimRight(fmt.Sprintf("%09d", nsec), "0")
+	return sign + strconv.FormatInt(sec, 10) + "." + frac
+}
+
+// parseDuration reads a time.Duration in time.ParseDuration syntax. The
+// "iso8601" option also accepts ISO 8601 durations such as `PT1H30M`, and
+// "unit=s" reads bare integers in the given unit.
+func parseDuration(value string, fieldName string, opts TagOptions) (time.Duration, error) {
+	if opts.Contains("unit") {
+		unit, err := durationUnit(opts["unit"])
+		if err != nil {
+			return 0, errors.New(fmt.Sprintf(unknownDurationUnit, opts["unit"], fieldName))
+		}
+
+		if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+			if i > math.MaxInt64/int64(unit) || i < math.MinInt64/int64(unit) {
+				return 0, errors.New(fmt.Sprintf(wrongDurationType, value, fieldName))
+			}
+			return time.Duration(i) * unit, nil
+		}
+	}
+
+	if opts.Contains("iso8601") && strings.Contains(value, "P") {
+		d, ok := parseISO8601Duration(value)
+		if !ok {
+			return 0, errors.New(fmt.Sprintf(wrongDurationType, value, fieldName))
+		}
+		return d, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, errors.New(fmt.Sprintf(wrongDurationType, value, fieldName))
+	}
+	return d, nil
+}
+
+// durationUnit returns the length of a unit accepted by time.ParseDuration.
+func durationUnit(unit string) (time.Duration, error) {
+	if unit == "" || strings.ContainsAny(unit, "0123456789.+-") {
+		return 0, errors.New("invalid unit")
+	}
+	return time.ParseDuration("1" + unit)
+}
+
+func parseISO8601Duration(value string) (time.Duration, bool) {
+	m := iso8601Duration.FindStringSubmatch(value)
+	if m == nil || value == "P" || value == "-P" || strings.HasSuffix(value, "T") {
+		return 0, false
+	}
+
+	var total float64
+	for i, unit := range []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute, time.Second} {
+		if m[i+2] == "" {
+			continue
+		}
+		n, err := strconv.ParseFloat(m[i+2], 64)
+		if err != nil {
+			return 0, false
+		}
+		total += n * float64(unit)
+	}
+
+	if total > math.MaxInt64 {
+		return 0, false
+	}
+	if m[1] == "-" {
+		total = -total
+	}
+	return time.Duration(total), true
+}
+
+// formatDuration is the reverse of parseDuration.
+func formatDuration(d time.Duration, opts TagOptions) string {
+	if opts.Contains("unit") {
+		if unit, err := durationUnit(opts["unit"]); err == nil && d%unit == 0 {
+			return strconv.FormatInt(int64(d/unit), 10)
+		}
+	}
+
+	if opts.Contains("iso8601") {
+		return formatISO8601Duration(d)
+	}
+
+	return d.String()
+}
+
+func formatISO8601Duration(d time.Duration) string {
+	if d == 0 {
+		return "PT0S"
+	}
+
+	var b strings.Builder
+	if d < 0 {
+		b.WriteString("-")
+		d = -d
+	}
+	b.WriteString("P")
+
+	if days := d / (24 * time.Hour); days > 0 {
+		fmt.Fprintf(&b, "%dD", days)
+		d -= days * 24 * time.Hour
+	}
+	if d == 0 {
+		return b.String()
+	}
+
+	b.WriteString("T")
+	if hours := d / time.Hour; hours > 0 {
+		fmt.Fprintf(&b, "%dH", hours)
+		d -= hours * time.Hour
+	}
+	if minutes := d / time.Minute; minutes > 0 {
+		fmt.Fprintf(&b, "%dM", minutes)
+		d -= minutes * time.Minute
+	}
+	if d > 0 {
+		b.WriteString(strconv.FormatFloat(d.Seconds(), 'f', -1, 64))
+		b.WriteString("S")
+	}
+
+	return b.String()
+}
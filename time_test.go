@@ -0,0 +1,283 @@
+package mapper_test
+
+import (
+	"github.com/assertis/url-mapper"
+	"github.com/stretchr/testify/assert"
+	"net/url"
+	"testing"
+	"time"
+)
+
+type TestDurationRequest struct {
+	MaxWait    time.Duration   `query:"max_wait"`
+	Connection time.Duration   `query:"connection,iso8601"`
+	Timeout    time.Duration   `query:"timeout,unit=s"`
+	Windows    []time.Duration `query:"window,unit=m,iso8601"`
+}
+
+func TestMappingDurations(t *testing.T) {
+	var r = TestDurationRequest{}
+
+	values, err := url.ParseQuery("max_wait=90m&connection=PT1H30M&timeout=90&window=15&window=PT1H&window=2h")
+	assert.Nil(t, err)
+
+	err = mapper.Unmarshal(values, &r)
+	assert.Nil(t, err)
+
+	assert.Equal(t, 90*time.Minute, r.MaxWait)
+	assert.Equal(t, 90*time.Minute, r.Connection)
+	assert.Equal(t, 90*time.Second, r.Timeout)
+	assert.Equal(t, []time.Duration{15 * time.Minute, time.Hour, 2 * time.Hour}, r.Windows)
+
+	values, err = url.ParseQuery("connection=-P1DT0.5S&timeout=1m30s")
+	assert.Nil(t, err)
+
+	err = mapper.Unmarshal(values, &r)
+	assert.Nil(t, err)
+
+	assert.Equal(t, -(24*time.Hour + 500*time.Millisecond), r.Connection)
+	assert.Equal(t, 90*time.Second, r.Timeout)
+}
+
+func TestIncorrectDurations(t *testing.T) {
+	var r = TestDurationRequest{}
+
+	for _, query := range []string{"max_wait=90", "max_wait=PT1H", "connection=P1Y", "connection=PT", "timeout=soon"} {
+		values, err := url.ParseQuery(query)
+		assert.Nil(t, err)
+
+		err = mapper.Unmarshal(values, &r)
+		assert.NotNil(t, err, query)
+	}
+
+	values, err := url.ParseQuery("max_wait=90")
+	assert.Nil(t, err)
+
+	err = mapper.Unmarshal(values, &r)
+	assert.EqualError(t, err, "Provided value `90` for field `MaxWait` is not a duration")
+
+	var bad struct {
+		Wait time.Duration `query:"wait,unit=fortnight"`
+	}
+	err = mapper.Unmarshal(url.Values{"wait": {"1"}}, &bad)
+	assert.EqualError(t, err, "Unknown duration unit `fortnight` for field `Wait`")
+}
+
+func TestMarshalDurations(t *testing.T) {
+	r := TestDurationRequest{
+		MaxWait:    90 * time.Minute,
+		Connection: 26*time.Hour + 1500*time.Millisecond,
+		Timeout:    90 * time.Second,
+		Windows:    []time.Duration{15 * time.Minute, 90 * time.Second},
+	}
+
+	values, err := mapper.Marshal(r)
+	assert.Nil(t, err)
+
+	assert.Equal(t, "1h30m0s", values.Get("max_wait"))
+	assert.Equal(t, "P1DT2H1.5S", values.Get("connection"))
+	assert.Equal(t, "90", values.Get("timeout"))
+	assert.Equal(t, []string{"15", "PT1M30S"}, values["window"])
+
+	var out TestDurationRequest
+	err = mapper.Unmarshal(values, &out)
+	assert.Nil(t, err)
+	assert.Equal(t, r, out)
+}
+
+type TestLayoutRequest struct {
+	Outward   time.Time `query:"outward,layout=2006-01-02"`
+	Inward    time.Time `query:"inward,formats=DateOnly|02/01/2006|2006-01-02T15:04"`
+	Departure time.Time `query:"departure,layout=Kitchen"`
+	Issued    time.Time `query:"issued,layout=RFC1123"`
+}
+
+func TestMappingTimeLayouts(t *testing.T) {
+	var r = TestLayoutRequest{}
+
+	values, err := url.ParseQuery("outward=2016-12-31&inward=31/12/2016&departure=11:05AM&issued=Sat, 31 Dec 2016 11:00:00 UTC")
+	assert.Nil(t, err)
+
+	err = mapper.Unmarshal(values, &r)
+	assert.Nil(t, err)
+
+	assert.Equal(t, time.Date(2016, 12, 31, 0, 0, 0, 0, time.UTC), r.Outward)
+	assert.Equal(t, time.Date(2016, 12, 31, 0, 0, 0, 0, time.UTC), r.Inward)
+	assert.Equal(t, time.Date(0, 1, 1, 11, 5, 0, 0, time.UTC), r.Departure)
+	assert.Equal(t, time.Date(2016, 12, 31, 11, 0, 0, 0, time.UTC), r.Issued)
+
+	for _, inward := range []string{"2016-12-31", "31/12/2016", "2016-12-31T11:00"} {
+		err = mapper.Unmarshal(url.Values{"inward": {inward}}, &r)
+		assert.Nil(t, err, inward)
+	}
+	assert.Equal(t, time.Date(2016, 12, 31, 11, 0, 0, 0, time.UTC), r.Inward)
+}
+
+func TestIncorrectTimeLayouts(t *testing.T) {
+	var r = TestLayoutRequest{}
+
+	values, err := url.ParseQuery("inward=12/31/2016")
+	assert.Nil(t, err)
+
+	err = mapper.Unmarshal(values, &r)
+	assert.EqualError(t, err, "Provided value `12/31/2016` for field `Inward` is not compatible with time or no format was provided")
+}
+
+func TestMarshalTimeLayouts(t *testing.T) {
+	date := time.Date(2016, 12, 31, 11, 0, 0, 0, time.UTC)
+	r := TestLayoutRequest{Outward: date, Inward: date, Departure: date, Issued: date}
+
+	values, err := mapper.Marshal(r)
+	assert.Nil(t, err)
+
+	assert.Equal(t, "2016-12-31", values.Get("outward"))
+	assert.Equal(t, "2016-12-31", values.Get("inward"))
+	assert.Equal(t, "11:00AM", values.Get("departure"))
+	assert.Equal(t, "Sat, 31 Dec 2016 11:00:00 UTC", values.Get("issued"))
+}
+
+type TestTimeZoneRequest struct {
+	Outward time.Time `query:"outward,layout=DateOnly,tz=Europe/London"`
+	Inward  time.Time `query:"inward,layout=DateOnly,tz=Europe/London,utc"`
+	Created time.Time `query:"created,unix"`
+	Updated time.Time `query:"updated,unix,utc"`
+}
+
+func TestMappingTimeZones(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	assert.Nil(t, err)
+
+	var r = TestTimeZoneRequest{}
+
+	values, err := url.ParseQuery("outward=2016-07-01&inward=2016-07-01&created=1467327600&updated=1467327600")
+	assert.Nil(t, err)
+
+	err = mapper.Unmarshal(values, &r)
+	assert.Nil(t, err)
+
+	assert.Equal(t, time.Date(2016, 7, 1, 0, 0, 0, 0, london), r.Outward)
+	assert.Equal(t, time.Date(2016, 6, 30, 23, 0, 0, 0, time.UTC), r.Inward)
+	assert.Equal(t, time.Unix(1467327600, 0), r.Created)
+	assert.Equal(t, time.Date(2016, 6, 30, 23, 0, 0, 0, time.UTC), r.Updated)
+
+	decoder := mapper.Decoder{Location: london}
+	err = decoder.Unmarshal(values, &r)
+	assert.Nil(t, err)
+
+	assert.Equal(t, time.Date(2016, 7, 1, 0, 0, 0, 0, london), r.Created)
+	assert.Equal(t, 1, r.Created.Day())
+}
+
+func TestIncorrectTimeZone(t *testing.T) {
+	var r struct {
+		Outward time.Time `query:"outward,layout=DateOnly,tz=Europe/Nowhere"`
+	}
+
+	err := mapper.Unmarshal(url.Values{"outward": {"2016-07-01"}}, &r)
+	assert.EqualError(t, err, "Unknown time zone `Europe/Nowhere` for field `Outward`")
+}
+
+func TestMarshalTimeZones(t *testing.T) {
+	r := TestTimeZoneRequest{
+		Outward: time.Date(2016, 6, 30, 23, 30, 0, 0, time.UTC),
+		Inward:  time.Date(2016, 6, 30, 23, 30, 0, 0, time.UTC),
+	}
+
+	values, err := mapper.Marshal(r)
+	assert.Nil(t, err)
+
+	assert.Equal(t, "2016-07-01", values.Get("outward"))
+	assert.Equal(t, "2016-06-30", values.Get("inward"))
+}
+
+type TestUnixRequest struct {
+	Seconds  time.Time `query:"s,unix,utc"`
+	Millis   time.Time `query:"ms,unixms,utc"`
+	Micros   time.Time `query:"us,unixus,utc"`
+	Nanos    time.Time `query:"ns,unixns,utc"`
+	Fraction time.Time `query:"frac,unixfrac,utc"`
+}
+
+func TestMappingUnixPrecisions(t *testing.T) {
+	var r = TestUnixRequest{}
+
+	values, err := url.ParseQuery("s=1482852746&ms=1482852746250&us=1482852746250000&ns=1482852746250000001&frac=1482852746.25")
+	assert.Nil(t, err)
+
+	err = mapper.Unmarshal(values, &r)
+	assert.Nil(t, err)
+
+	expected := time.Unix(1482852746, 250000000).UTC()
+	assert.Equal(t, time.Unix(1482852746, 0).UTC(), r.Seconds)
+	assert.Equal(t, expected, r.Millis)
+	assert.Equal(t, expected, r.Micros)
+	assert.Equal(t, expected.Add(time.Nanosecond), r.Nanos)
+	assert.Equal(t, expected, r.Fraction)
+
+	err = mapper.Unmarshal(url.Values{"frac": {"-1.5"}}, &r)
+	assert.Nil(t, err)
+	assert.Equal(t, time.Unix(-2, 500000000).UTC(), r.Fraction)
+
+	out, err := mapper.Marshal(r)
+	assert.Nil(t, err)
+	assert.Equal(t, "1482852746250", out.Get("ms"))
+	assert.Equal(t, "1482852746250000", out.Get("us"))
+	assert.Equal(t, "1482852746250000001", out.Get("ns"))
+	assert.Equal(t, "-1.5", out.Get("frac"))
+}
+
+func TestIncorrectUnixPrecisions(t *testing.T) {
+	var r = TestUnixRequest{}
+
+	values, err := url.ParseQuery("s=1482852746250")
+	assert.Nil(t, err)
+
+	err = mapper.Unmarshal(values, &r)
+	assert.EqualError(t, err, "Provided value `1482852746250` for field `Seconds` is out of the supported time range")
+
+	values, err = url.ParseQuery("ms=1482852746")
+	assert.Nil(t, err)
+
+	err = mapper.Unmarshal(values, &r)
+	assert.EqualError(t, err, "Provided value `1482852746` for field `Millis` is too close to the Unix epoch, it may be in the wrong unit")
+
+	for _, query := range []string{"ms=1482852746250000000", "us=1482852746250", "ns=-1482852746250000", "frac=1.2.3", "frac=1.1234567891", "us=1.5", "s=99999999999999999999"} {
+		values, err := url.ParseQuery(query)
+		assert.Nil(t, err)
+
+		err = mapper.Unmarshal(values, &r)
+		assert.NotNil(t, err, query)
+	}
+}
+
+func TestMappingUnixRangeInOtherZones(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	assert.Nil(t, err)
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	assert.Nil(t, err)
+
+	var r struct {
+		T time.Time `query:"t,unix"`
+		Z time.Time `query:"z,unix,tz=America/Los_Angeles"`
+	}
+
+	values, err := mapper.Marshal(r)
+	assert.Nil(t, err)
+	assert.Equal(t, "-62135596800", values.Get("t"))
+	assert.Equal(t, "-62135596800", values.Get("z"))
+
+	for _, loc := range []*time.Location{nil, newYork, tokyo} {
+		decoder := mapper.Decoder{Location: loc}
+
+		err = decoder.Unmarshal(values, &r)
+		assert.Nil(t, err)
+		assert.True(t, r.T.Equal(time.Time{}))
+		assert.True(t, r.Z.Equal(time.Time{}))
+
+		last := "253402300799"
+		err = decoder.Unmarshal(url.Values{"t": {last}, "z": {last}}, &r)
+		assert.Nil(t, err)
+		assert.Equal(t, time.Date(9999, 12, 31, 23, 59, 59, 0, time.UTC), r.T.UTC())
+		assert.Equal(t, time.Date(9999, 12, 31, 23, 59, 59, 0, time.UTC), r.Z.UTC())
+	}
+}
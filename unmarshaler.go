@@ -0,0 +1,118 @@
+package mapper
+
+import (
+	"encoding"
+	"reflect"
+)
+
+// QueryUnmarshaler is implemented by types that decode themselves from all
+// values provided for their key.
+type QueryUnmarshaler interface {
+	UnmarshalQuery([]string) error
+}
+
+// QueryMarshaler is implemented by types that encode themselves into the
+// values of their key.
+type QueryMarshaler interface {
+	MarshalQuery() ([]string, error)
+}
+
+var (
+	queryUnmarshalerType = reflect.TypeOf((*QueryUnmarshaler)(nil)).Elem()
+	queryMarshalerType   = reflect.TypeOf((*QueryMarshaler)(nil)).Elem()
+	textUnmarshalerType  = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	textMarshalerType    = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// isCustomType reports whether t, or a pointer to it, has a registered
+// converter or implements one of the interfaces that take over decoding or
+// encoding from the mapper. time.Time is excluded from the latter since its
+// format is chosen through tag options.
+func isCustomType(t reflect.Type) bool {
+	if isRegisteredType(t) || isRegisteredType(reflect.PtrTo(t)) {
+		return true
+	}
+	if t == timeType {
+		return false
+	}
+
+	for _, i := range []reflect.Type{queryUnmarshalerType, queryMarshalerType, textUnmarshalerType, textMarshalerType} {
+		if t.Implements(i) || reflect.PtrTo(t).Implements(i) {
+			return true
+		}
+	}
+	return false
+}
+
+// queryUnmarshaler returns the QueryUnmarshaler implemented by v or, when v is
+// addressable, by a pointer to it.
+func queryUnmarshaler(v reflect.Value) (QueryUnmarshaler, bool) {
+	if v.CanAddr() {
+		if u, ok := v.Addr().Interface().(QueryUnmarshaler); ok {
+			return u, true
+		}
+	}
+	if !v.CanInterface() {
+		return nil, false
+	}
+	u, ok := v.Interface().(QueryUnmarshaler)
+	return u, ok
+}
+
+// textUnmarshaler is like queryUnmarshaler for encoding.TextUnmarshaler.
+// time.Time is left to the mapper.
+func textUnmarshaler(v reflect.Value) (encoding.TextUnmarshaler, bool) {
+	if v.Type() == timeType {
+		return nil, false
+	}
+
+	if v.CanAddr() {
+		if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+			return u, true
+		}
+	}
+	if !v.CanInterface() {
+		return nil, false
+	}
+	u, ok := v.Interface().(encoding.TextUnmarshaler)
+	return u, ok
+}
+
+// addressable returns v itself when it is addressable, or an addressable
+// copy of it, so that methods with pointer receivers can be called.
+func addressable(v reflect.Value) reflect.Value {
+	if v.CanAddr() {
+		return v
+	}
+	c := reflect.New(v.Type()).Elem()
+	c.Set(v)
+	return c
+}
+
+// queryMarshaler returns the QueryMarshaler implemented by v or a pointer to
+// it.
+func queryMarshaler(v reflect.Value) (QueryMarshaler, bool) {
+	if m, ok := v.Interface().(QueryMarshaler); ok {
+		return m, true
+	}
+	if reflect.PtrTo(v.Type()).Implements(queryMarshalerType) {
+		return addressable(v).Addr().Interface().(QueryMarshaler), true
+	}
+	return nil, false
+}
+
+// textMarshaler is like queryMarshaler for encoding.TextMarshaler.
+// time.Time is left to the mapper.
+func textMarshaler(v reflect.Value) (encoding.TextMarshaler, bool) {
+	if v.Type() == timeType {
+		return nil, false
+	}
+
+	if m, ok := v.Interface().(encoding.TextMarshaler); ok {
+		return m, true
+	}
+	if reflect.PtrTo(v.Type()).Implements(textMarshalerType) {
+		return addressable(v).Addr().Interface().(encoding.TextMarshaler), true
+	}
+	return nil, false
+}
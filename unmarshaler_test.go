@@ -0,0 +1,106 @@
+package mapper_test
+
+import (
+	"errors"
+	"github.com/assertis/url-mapper"
+	"github.com/stretchr/testify/assert"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+type TestStationCode string
+
+func (c *TestStationCode) UnmarshalText(text []byte) error {
+	if len(text) != 3 {
+		return errors.New("station codes have three letters")
+	}
+	*c = TestStationCode(strings.ToUpper(string(text)))
+	return nil
+}
+
+func (c TestStationCode) MarshalText() ([]byte, error) {
+	return []byte(strings.ToLower(string(c))), nil
+}
+
+type TestFareClasses struct {
+	Standard bool
+	First    bool
+}
+
+func (f *TestFareClasses) UnmarshalQuery(values []string) error {
+	for _, value := range values {
+		switch value {
+		case "std":
+			f.Standard = true
+		case "first":
+			f.First = true
+		default:
+			return errors.New("unknown fare class")
+		}
+	}
+	return nil
+}
+
+func (f TestFareClasses) MarshalQuery() ([]string, error) {
+	var values []string
+	if f.Standard {
+		values = append(values, "std")
+	}
+	if f.First {
+		values = append(values, "first")
+	}
+	return values, nil
+}
+
+type TestCustomRequest struct {
+	Origin  TestStationCode   `query:"o"`
+	Via     []TestStationCode `query:"via"`
+	Return  *TestStationCode  `query:"r"`
+	Classes TestFareClasses   `query:"class"`
+}
+
+func TestMappingUnmarshalers(t *testing.T) {
+	var r = TestCustomRequest{}
+
+	values, err := url.ParseQuery("o=tbw&via=lbg&via=vic&r=ecr&class=std&class=first")
+	assert.Nil(t, err)
+
+	err = mapper.Unmarshal(values, &r)
+	assert.Nil(t, err)
+
+	assert.Equal(t, TestStationCode("TBW"), r.Origin)
+	assert.Equal(t, []TestStationCode{"LBG", "VIC"}, r.Via)
+	if assert.NotNil(t, r.Return) {
+		assert.Equal(t, TestStationCode("ECR"), *r.Return)
+	}
+	assert.Equal(t, TestFareClasses{Standard: true, First: true}, r.Classes)
+}
+
+func TestIncorrectUnmarshalerValues(t *testing.T) {
+	var r = TestCustomRequest{}
+
+	values, err := url.ParseQuery("via=lbg&via=london")
+	assert.Nil(t, err)
+
+	err = mapper.Unmarshal(values, &r)
+	assert.EqualError(t, err, "Provided value `london` for field `Via[1]` is not valid: station codes have three letters")
+
+	values, err = url.ParseQuery("class=std&class=sleeper")
+	assert.Nil(t, err)
+
+	err = mapper.Unmarshal(values, &r)
+	assert.EqualError(t, err, "Provided value `std,sleeper` for field `Classes` is not valid: unknown fare class")
+}
+
+func TestMarshalMarshalers(t *testing.T) {
+	r := TestCustomRequest{
+		Origin:  "TBW",
+		Via:     []TestStationCode{"LBG"},
+		Classes: TestFareClasses{First: true},
+	}
+
+	values, err := mapper.Marshal(r)
+	assert.Nil(t, err)
+	assert.Equal(t, url.Values{"o": {"tbw"}, "via": {"lbg"}, "class": {"first"}}, values)
+}
@@ -0,0 +1,649 @@
+package mapper
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"net/url"
+	"reflect"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+var (
+	requiredField    = "Field `%s` is required"
+	valueTooSmall    = "Provided value `%s` for field `%s` is less than %s"
+	valueTooLarge    = "Provided value `%s` for field `%s` is greater than %s"
+	lengthTooShort   = "Field `%s` has %d %s, expected at least %s"
+	lengthTooLong    = "Field `%s` has %d %s, expected at most %s"
+	wrongLength      = "Field `%s` has %d %s, expected %s"
+	patternMismatch  = "Provided value `%s` for field `%s` does not match `%s`"
+	notOneOf         = "Provided value `%s` for field `%s` is not one of `%s`"
+	invalidRuleParam = "Invalid value `%s` for option `%s` of field `%s`"
+	unsupportedRule  = "Option `%s` is not supported for field `%s`"
+	unknownValidator = "Unknown validator `%s` for field `%s`"
+	failedValidator  = "Provided value `%s` for field `%s` is not a valid %s"
+	unknownField     = "Unknown field `%s` in option `%s` of field `%s`"
+	patternCut       = "Option `%s` following the pattern of field `%s` is not an option, escape commas in patterns as `\\,`"
+
+	// Messages of the cross-field options, given the names of both fields.
+	crossFieldMessages = map[string]string{
+		"gtfield":          "Field `%s` must be greater than `%s`",
+		"gtefield":         "Field `%s` must be greater than or equal to `%s`",
+		"ltfield":          "Field `%s` must be less than `%s`",
+		"ltefield":         "Field `%s` must be less than or equal to `%s`",
+		"eqfield":          "Field `%s` must be equal to `%s`",
+		"nefield":          "Field `%s` must not be equal to `%s`",
+		"required_with":    "Field `%s` is required when `%s` is provided",
+		"required_without": "Field `%s` is required when `%s` is not provided",
+		"excluded_with":    "Field `%s` must not be provided together with `%s`",
+	}
+)
+
+// FieldError describes a field whose value failed one of the validation
+// options of its tag.
+type FieldError struct {
+	// Field is the Go path of the field, e.g. `Outward.Station` or
+	// `Passengers[0].Age`.
+	Field string
+	// Key is the query key the field is read from.
+	Key string
+	// Rule is the tag option that failed, e.g. `min`.
+	Rule string
+	// Param is the value of that option, e.g. `1` for `min=1`.
+	Param string
+	// Message describes the failure.
+	Message string
+}
+
+func (e *FieldError) Error() string {
+	return e.Message
+}
+
+// ValidationErrors is returned by Unmarshal when decoding succeeded but some
+// fields failed validation. It holds one FieldError per failure, in field
+// order.
+type ValidationErrors []*FieldError
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Validator is implemented by structs with rules too complex for tag
+// options. Unmarshal calls Validate once a struct is decoded.
+type Validator interface {
+	Validate() error
+}
+
+// err returns e as an error, or nil when it is empty.
+func (e ValidationErrors) err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// collect appends the failures held by err to errs, so that validation can
+// carry on with the remaining fields. Any other error is returned as is.
+func collect(errs *ValidationErrors, err error) error {
+	if fieldErrs, ok := err.(ValidationErrors); ok {
+		*errs = append(*errs, fieldErrs...)
+		return nil
+	}
+	return err
+}
+
+// validateField checks the value decoded into v against the validation
+// options of its tag, in this order:
+//
+//	required         the key must be present with a non-empty value
+//	min=N, max=N     bounds for numbers, or for the length of strings, slices and maps
+//	len=N            exact length of strings, slices and maps
+//	pattern=REGEXP   strings must match the regular expression
+//	oneof=a|b        the value must be one of those listed
+//	validate=a|b     strings must pass each of the named validators, see RegisterValidator
+//
+// Bounds of integer fields are read like their values, so `min=0.50` works
+// with the "money" option and `max=2h` for a time.Duration. Slices and arrays
+// of scalars are checked element by element for pattern, oneof and validate.
+// Commas separate tag options, so a comma in a pattern must be escaped as
+// `\,` unless it is within braces, as in `pattern=^[A-Z]{2,3}$`.
+//
+// Fields whose key is absent are only checked for required. A rule that does
+// not apply to the field's type, or has an invalid value, is returned as an
+// error instead of a FieldError.
+func (d *Decoder) validateField(values url.Values, v reflect.Value, key, fieldName string, t reflect.Type, opts TagOptions) (ValidationErrors, error) {
+	var errs ValidationErrors
+	fail := func(name, rule, param, message string) {
+		errs = append(errs, &FieldError{Field: name, Key: key, Rule: rule, Param: param, Message: message})
+	}
+
+	if !hasRules(opts) {
+		return nil, nil
+	}
+
+	v = validationTarget(v)
+	if !d.present(values, key, t, opts) {
+		// A default stands in for an absent key.
+		if opts.Contains("required") && !opts.Contains("default") && (!v.IsValid() || isNestedType(t) || isEmptyValue(v)) {
+			fail(fieldName, "required", "", fmt.Sprintf(requiredField, fieldName))
+		}
+		return errs, nil
+	}
+	if !v.IsValid() {
+		return nil, nil
+	}
+
+	raw := values.Get(key)
+	for _, rule := range []string{"min", "max", "len"} {
+		if !opts.Contains(rule) {
+			continue
+		}
+
+		param := opts[rule]
+		cmp, length, err := d.compareTo(v, param, rule, fieldName, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		unit := "values"
+		if v.Kind() == reflect.String {
+			unit = "characters"
+		}
+
+		switch {
+		case length < 0 && rule == "min" && cmp < 0:
+			fail(fieldName, rule, param, fmt.Sprintf(valueTooSmall, raw, fieldName, param))
+		case length < 0 && rule == "max" && cmp > 0:
+			fail(fieldName, rule, param, fmt.Sprintf(valueTooLarge, raw, fieldName, param))
+		case length >= 0 && rule == "min" && cmp < 0:
+			fail(fieldName, rule, param, fmt.Sprintf(lengthTooShort, fieldName, length, unit, param))
+		case length >= 0 && rule == "max" && cmp > 0:
+			fail(fieldName, rule, param, fmt.Sprintf(lengthTooLong, fieldName, length, unit, param))
+		case rule == "len" && cmp != 0:
+			fail(fieldName, rule, param, fmt.Sprintf(wrongLength, fieldName, length, unit, param))
+		}
+	}
+
+	if !opts.Contains("pattern") && !opts.Contains("oneof") && !opts.Contains("validate") {
+		return errs, nil
+	}
+
+	elems, raws, names := []reflect.Value{v}, []string{raw}, []string{fieldName}
+	if (v.Kind() == reflect.Slice || v.Kind() == reflect.Array) && !isCustomType(v.Type()) {
+		raws, _ = d.listValues(values, key, opts)
+		elems, names = nil, nil
+		for i := 0; i < v.Len(); i++ {
+			elems = append(elems, validationTarget(v.Index(i)))
+			names = append(names, fmt.Sprintf("%s[%d]", fieldName, i))
+		}
+	}
+
+	for i, elem := range elems {
+		if !elem.IsValid() {
+			continue
+		}
+		elemRaw := ""
+		if i < len(raws) {
+			elemRaw = raws[i]
+		}
+
+		if opts.Contains("pattern") {
+			if elem.Kind() != reflect.String {
+				return nil, errors.New(fmt.Sprintf(unsupportedRule, "pattern", fieldName))
+			}
+			re, err := compilePattern(opts["pattern"], fieldName)
+			if err != nil {
+				return nil, err
+			}
+			if !re.MatchString(elem.String()) {
+				fail(names[i], "pattern", opts["pattern"], fmt.Sprintf(patternMismatch, elemRaw, names[i], opts["pattern"]))
+			}
+		}
+
+		if opts.Contains("oneof") {
+			ok, err := d.oneOf(elem, fieldName, opts)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				fail(names[i], "oneof", opts["oneof"], fmt.Sprintf(notOneOf, elemRaw, names[i], opts["oneof"]))
+			}
+		}
+
+		if opts.Contains("validate") {
+			if elem.Kind() != reflect.String {
+				return nil, errors.New(fmt.Sprintf(unsupportedRule, "validate", fieldName))
+			}
+			for _, name := range strings.Split(opts["validate"], "|") {
+				fn, ok := validatorFor(name)
+				if !ok {
+					return nil, errors.New(fmt.Sprintf(unknownValidator, name, fieldName))
+				}
+				if !fn(elem.String()) {
+					fail(names[i], "validate", name, fmt.Sprintf(failedValidator, elemRaw, names[i], name))
+				}
+			}
+		}
+	}
+
+	return errs, nil
+}
+
+func hasRules(opts TagOptions) bool {
+	for _, rule := range []string{"required", "min", "max", "len", "pattern", "oneof", "validate"} {
+		if opts.Contains(rule) {
+			return true
+		}
+	}
+	return false
+}
+
+// validationTarget dereferences pointers down to the value they point to.
+// The result is not valid when one of them is nil.
+func validationTarget(v reflect.Value) reflect.Value {
+	for v.IsValid() && v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		if _, ok := converterFor(v.Type()); ok {
+			break
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+// compareTo compares v with the parameter of a min, max or len option. For
+// strings, slices, arrays and maps it is their length that is compared and
+// returned; for numbers the returned length is -1.
+func (d *Decoder) compareTo(v reflect.Value, param, rule, fieldName string, opts TagOptions) (int, int, error) {
+	invalid := errors.New(fmt.Sprintf(invalidRuleParam, param, rule, fieldName))
+
+	length := -1
+	switch v.Kind() {
+	case reflect.String:
+		length = utf8.RuneCountInString(v.String())
+	case reflect.Slice, reflect.Array, reflect.Map:
+		length = v.Len()
+	}
+	if length >= 0 {
+		limit, err := strconv.Atoi(param)
+		if err != nil || limit < 0 {
+			return 0, 0, invalid
+		}
+		return compareInts(int64(length), int64(limit)), length, nil
+	}
+
+	if rule == "len" {
+		return 0, 0, errors.New(fmt.Sprintf(unsupportedRule, rule, fieldName))
+	}
+
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.Type() == durationType {
+			limit, err := parseDuration(param, fieldName, opts)
+			if err != nil {
+				return 0, 0, invalid
+			}
+			return compareInts(v.Int(), int64(limit)), -1, nil
+		}
+
+		digits, base, err := intDigits(param, fieldName, opts)
+		if err != nil {
+			return 0, 0, invalid
+		}
+		limit, err := strconv.ParseInt(digits, base, 64)
+		if err != nil {
+			return 0, 0, invalid
+		}
+		return compareInts(v.Int(), limit), -1, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		digits, base, err := intDigits(param, fieldName, opts)
+		if err != nil {
+			return 0, 0, invalid
+		}
+		limit, err := strconv.ParseUint(digits, base, 64)
+		if err != nil {
+			return 0, 0, invalid
+		}
+		switch {
+		case v.Uint() < limit:
+			return -1, -1, nil
+		case v.Uint() > limit:
+			return 1, -1, nil
+		}
+		return 0, -1, nil
+	case reflect.Float32, reflect.Float64:
+		limit, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			return 0, 0, invalid
+		}
+		switch {
+		case v.Float() < limit:
+			return -1, -1, nil
+		case v.Float() > limit:
+			return 1, -1, nil
+		}
+		return 0, -1, nil
+	}
+
+	return 0, 0, errors.New(fmt.Sprintf(unsupportedRule, rule, fieldName))
+}
+
+func compareInts(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// oneOf reports whether v equals one of the values listed in the "oneof"
+// option, each converted like a query value for the field.
+func (d *Decoder) oneOf(v reflect.Value, fieldName string, opts TagOptions) (bool, error) {
+	for _, option := range strings.Split(opts["oneof"], "|") {
+		if v.Kind() == reflect.String {
+			if v.String() == option {
+				return true, nil
+			}
+			continue
+		}
+
+		allowed := reflect.New(v.Type()).Elem()
+		if err := d.setValue(allowed, option, fieldName, opts); err != nil {
+			return false, errors.New(fmt.Sprintf(invalidRuleParam, opts["oneof"], "oneof", fieldName))
+		}
+		if reflect.DeepEqual(v.Interface(), allowed.Interface()) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+var optionName = regexp.MustCompile(`^[a-z][a-z0-9_]*(=|$)`)
+
+// checkPattern reports an option following the "pattern" option of the tag
+// that does not look like one, which is most likely the rest of a pattern
+// cut at an unescaped comma.
+func checkPattern(tag, fieldName string) error {
+	options := splitOptions(tag)
+	for i := 1; i < len(options)-1; i++ {
+		if strings.HasPrefix(options[i], "pattern=") && !optionName.MatchString(options[i+1]) {
+			return errors.New(fmt.Sprintf(patternCut, options[i+1], fieldName))
+		}
+	}
+	return nil
+}
+
+var patternCache sync.Map // map[string]*regexp.Regexp
+
+func compilePattern(pattern, fieldName string) (*regexp.Regexp, error) {
+	if re, ok := patternCache.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf(invalidRuleParam, pattern, "pattern", fieldName))
+	}
+
+	patternCache.Store(pattern, re)
+	return re, nil
+}
+
+// validateCrossFields checks the options of the fields of the struct v that
+// refer to other fields of the same struct, once all of them are decoded:
+//
+//	gtfield=F, gtefield=F    the value must be greater than (or equal to) field F
+//	ltfield=F, ltefield=F    the value must be less than (or equal to) field F
+//	eqfield=F, nefield=F     the value must (not) be equal to field F
+//	required_with=F|G        the key is required when F or G is provided
+//	required_without=F|G     the key is required when F or G is not provided
+//	excluded_with=F|G        the key must be absent when F or G is provided
+//
+// Fields are named by their Go name. Comparisons accept a multiplier for
+// numbers, as in `ltefield=Adults*4`, and are skipped when either key is
+// absent. Times, numbers of any kind and strings can be compared.
+func (d *Decoder) validateCrossFields(values url.Values, v reflect.Value, prefix, path string) (ValidationErrors, error) {
+	fields := cachedTypeFields(v.Type())
+
+	var errs ValidationErrors
+	for _, f := range fields {
+		for _, rule := range []string{"gtfield", "gtefield", "ltfield", "ltefield", "eqfield", "nefield", "required_with", "required_without", "excluded_with"} {
+			if !f.opts.Contains(rule) {
+				continue
+			}
+
+			fieldName := joinKey(path, ".", f.path)
+			key := d.key(prefix, f.name)
+			present := d.present(values, key, f.typ, f.opts)
+
+			fail := func(other string) {
+				message := fmt.Sprintf(crossFieldMessages[rule], fieldName, joinKey(path, ".", other))
+				errs = append(errs, &FieldError{Field: fieldName, Key: key, Rule: rule, Param: f.opts[rule], Message: message})
+			}
+
+			var others []string
+			if strings.HasSuffix(rule, "field") {
+				others = []string{f.opts[rule]}
+			} else {
+				others = strings.Split(f.opts[rule], "|")
+			}
+
+			for _, other := range others {
+				name, factor := other, ""
+				if i := strings.Index(other, "*"); i >= 0 && strings.HasSuffix(rule, "field") {
+					name, factor = other[:i], other[i+1:]
+				}
+
+				o, ok := fieldByName(fields, name)
+				if !ok {
+					return nil, errors.New(fmt.Sprintf(unknownField, name, rule, fieldName))
+				}
+				otherPresent := d.present(values, d.key(prefix, o.name), o.typ, o.opts)
+
+				switch rule {
+				case "required_with":
+					if otherPresent && !present {
+						fail(other)
+					}
+					continue
+				case "required_without":
+					if !otherPresent && !present {
+						fail(other)
+					}
+					continue
+				case "excluded_with":
+					if otherPresent && present {
+						fail(other)
+					}
+					continue
+				}
+
+				if !present || !otherPresent {
+					continue
+				}
+
+				a := validationTarget(fieldByIndex(v, f.index, false))
+				b := validationTarget(fieldByIndex(v, o.index, false))
+				if !a.IsValid() || !b.IsValid() {
+					continue
+				}
+
+				cmp, err := compareFields(a, b, factor, rule, fieldName)
+				if err != nil {
+					return nil, err
+				}
+
+				var valid bool
+				switch rule {
+				case "gtfield":
+					valid = cmp > 0
+				case "gtefield":
+					valid = cmp >= 0
+				case "ltfield":
+					valid = cmp < 0
+				case "ltefield":
+					valid = cmp <= 0
+				case "eqfield":
+					valid = cmp == 0
+				case "nefield":
+					valid = cmp != 0
+				}
+				if !valid {
+					fail(other)
+				}
+			}
+		}
+	}
+
+	return errs, nil
+}
+
+// fieldByName finds the field with the given Go name, or Go path for fields
+// promoted from embedded structs.
+func fieldByName(fields []field, name string) (field, bool) {
+	for _, f := range fields {
+		if f.path == name || f.path[strings.LastIndex(f.path, ".")+1:] == name {
+			return f, true
+		}
+	}
+	return field{}, false
+}
+
+// compareFields compares a with b multiplied by factor, if one is given.
+func compareFields(a, b reflect.Value, factor, rule, fieldName string) (int, error) {
+	unsupported := errors.New(fmt.Sprintf(unsupportedRule, rule, fieldName))
+
+	if a.Type() == timeType && b.Type() == timeType && factor == "" {
+		return a.Interface().(time.Time).Compare(b.Interface().(time.Time)), nil
+	}
+
+	if a.Kind() == reflect.String && b.Kind() == reflect.String && factor == "" {
+		return strings.Compare(a.String(), b.String()), nil
+	}
+
+	x, ok := ratOf(a)
+	if !ok {
+		return 0, unsupported
+	}
+	y, ok := ratOf(b)
+	if !ok {
+		return 0, unsupported
+	}
+	if factor != "" {
+		r, ok := new(big.Rat).SetString(factor)
+		if !ok {
+			return 0, errors.New(fmt.Sprintf(invalidRuleParam, factor, rule, fieldName))
+		}
+		y.Mul(y, r)
+	}
+	return x.Cmp(y), nil
+}
+
+// ratOf returns the number held by v exactly.
+func ratOf(v reflect.Value) (*big.Rat, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return new(big.Rat).SetInt64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return new(big.Rat).SetInt(new(big.Int).SetUint64(v.Uint())), true
+	case reflect.Float32, reflect.Float64:
+		if math.IsNaN(v.Float()) || math.IsInf(v.Float(), 0) {
+			return nil, false
+		}
+		return new(big.Rat).SetFloat64(v.Float()), true
+	}
+	return nil, false
+}
+
+// validateStruct calls Validate on the struct v, after calling it on each
+// struct promoted into v. Go promotes the method of an embedded struct to
+// v as well, so v's own method is only called when v declares it itself;
+// this way every method runs once, innermost first. Structs embedded with a
+// key of their own are nested fields, validated by mapToStruct.
+//
+// Returned ValidationErrors and FieldErrors are kept as they are; any other
+// error becomes a FieldError for the struct with the rule "Validate".
+func validateStruct(v reflect.Value, prefix, path string) ValidationErrors {
+	var errs ValidationErrors
+
+	for i := 0; i < v.NumField(); i++ {
+		sf := v.Type().Field(i)
+		name, opts := TagOptionsFromString(sf.Tag.Get("query"))
+
+		embedded := sf.Anonymous && name == ""
+		if !embedded && !opts.Contains("inline") && !opts.Contains("prefix") {
+			continue
+		}
+
+		fv := v.Field(i)
+		if fv.Kind() == reflect.Ptr {
+			if fv.IsNil() {
+				continue
+			}
+			fv = fv.Elem()
+		}
+		if !isStructType(fv.Type()) || !fv.CanInterface() {
+			continue
+		}
+
+		errs = append(errs, validateStruct(fv, prefix, joinKey(path, ".", sf.Name))...)
+	}
+
+	validator, ok := addressable(v).Addr().Interface().(Validator)
+	if !ok || !declaresValidate(v.Type()) {
+		return errs
+	}
+
+	switch err := validator.Validate().(type) {
+	case nil:
+	case ValidationErrors:
+		errs = append(errs, err...)
+	case *FieldError:
+		errs = append(errs, err)
+	default:
+		errs = append(errs, &FieldError{Field: path, Key: prefix, Rule: "Validate", Message: err.Error()})
+	}
+	return errs
+}
+
+var declaredCache sync.Map // map[reflect.Type]bool
+
+// declaresValidate reports whether the struct type t, or a pointer to it,
+// declares the Validate method itself rather than having it promoted from
+// an embedded field. The compiler implements promoted methods as wrappers,
+// which the runtime reports as autogenerated.
+func declaresValidate(t reflect.Type) bool {
+	if declared, ok := declaredCache.Load(t); ok {
+		return declared.(bool)
+	}
+
+	m, ok := t.MethodByName("Validate")
+	if !ok {
+		m, ok = reflect.PtrTo(t).MethodByName("Validate")
+	}
+
+	declared := false
+	if ok {
+		pc := m.Func.Pointer()
+		file, _ := runtime.FuncForPC(pc).FileLine(pc)
+		declared = file != "<autogenerated>"
+	}
+
+	declaredCache.Store(t, declared)
+	return declared
+}
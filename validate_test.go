@@ -0,0 +1,404 @@
+package mapper_test
+
+import (
+	"errors"
+	"github.com/assertis/url-mapper"
+	"github.com/stretchr/testify/assert"
+	"net/url"
+	"testing"
+	"time"
+)
+
+type TestValidatedLeg struct {
+	Station string `query:"station,required,len=3"`
+}
+
+type TestValidatedRequest struct {
+	Origin   string            `query:"origin,required,pattern=^[A-Z]{3}$"`
+	Adults   int               `query:"adults,min=1,max=9"`
+	Class    string            `query:"class,oneof=std|first"`
+	Name     string            `query:"name,min=2,max=5"`
+	Railcard *string           `query:"railcard,len=3"`
+	Fare     int64             `query:"fare,money,min=0.50"`
+	Window   time.Duration     `query:"window,max=2h"`
+	Ages     []int             `query:"ages,max=2,oneof=1|2|3"`
+	Codes    []string          `query:"codes,sep=,,pattern=^[a-z]+$"`
+	Outward  TestValidatedLeg  `query:"outward"`
+	Inward   *TestValidatedLeg `query:"inward"`
+}
+
+func TestValidation(t *testing.T) {
+	var r = TestValidatedRequest{}
+
+	values, err := url.ParseQuery("origin=TBW&adults=2&class=first&name=Ann&railcard=YNG&fare=1.00&window=90m&ages=1&ages=3&codes=ab,cd&outward.station=LBG")
+	assert.Nil(t, err)
+
+	err = mapper.Unmarshal(values, &r)
+	assert.Nil(t, err)
+
+	assert.Equal(t, "TBW", r.Origin)
+	assert.Equal(t, 2, r.Adults)
+	assert.Equal(t, "YNG", *r.Railcard)
+	assert.Equal(t, []int{1, 3}, r.Ages)
+	assert.Equal(t, "LBG", r.Outward.Station)
+	assert.Nil(t, r.Inward)
+}
+
+func TestValidationFailures(t *testing.T) {
+	failures := map[string]string{
+		"adults=1":                                            "Field `Origin` is required; Field `Outward.Station` is required",
+		"origin=tbw&outward.station=LBG":                      "Provided value `tbw` for field `Origin` does not match `^[A-Z]{3}$`",
+		"origin=TBW&adults=0&outward.station=LBG":             "Provided value `0` for field `Adults` is less than 1",
+		"origin=TBW&adults=10&outward.station=LBG":            "Provided value `10` for field `Adults` is greater than 9",
+		"origin=TBW&class=third&outward.station=LBG":          "Provided value `third` for field `Class` is not one of `std|first`",
+		"origin=TBW&name=A&outward.station=LBG":               "Field `Name` has 1 characters, expected at least 2",
+		"origin=TBW&name=Ánnabel&outward.station=LBG":         "Field `Name` has 7 characters, expected at most 5",
+		"origin=TBW&railcard=YP&outward.station=LBG":          "Field `Railcard` has 2 characters, expected 3",
+		"origin=TBW&fare=0.25&outward.station=LBG":            "Provided value `0.25` for field `Fare` is less than 0.50",
+		"origin=TBW&window=3h&outward.station=LBG":            "Provided value `3h` for field `Window` is greater than 2h",
+		"origin=TBW&ages=1&ages=2&ages=3&outward.station=LBG": "Field `Ages` has 3 values, expected at most 2",
+		"origin=TBW&ages=1&ages=4&outward.station=LBG":        "Provided value `4` for field `Ages[1]` is not one of `1|2|3`",
+		"origin=TBW&codes=ab,CD&outward.station=LBG":          "Provided value `CD` for field `Codes[1]` does not match `^[a-z]+$`",
+		"origin=TBW&outward.station=LONDON":                   "Field `Outward.Station` has 6 characters, expected 3",
+		"origin=TBW&outward.station=LBG&inward.station=X":     "Field `Inward.Station` has 1 characters, expected 3",
+	}
+	for query, expected := range failures {
+		values, err := url.ParseQuery(query)
+		assert.Nil(t, err)
+
+		var r = TestValidatedRequest{}
+		err = mapper.Unmarshal(values, &r)
+		assert.EqualError(t, err, expected, query)
+	}
+}
+
+func TestValidationErrorsPerField(t *testing.T) {
+	var r = TestValidatedRequest{}
+
+	values, err := url.ParseQuery("origin=tbw&adults=0&outward.station=LBG")
+	assert.Nil(t, err)
+
+	err = mapper.Unmarshal(values, &r)
+	fieldErrs, ok := err.(mapper.ValidationErrors)
+	assert.True(t, ok)
+	assert.Equal(t, 2, len(fieldErrs))
+
+	assert.Equal(t, "Origin", fieldErrs[0].Field)
+	assert.Equal(t, "origin", fieldErrs[0].Key)
+	assert.Equal(t, "pattern", fieldErrs[0].Rule)
+	assert.Equal(t, "Adults", fieldErrs[1].Field)
+	assert.Equal(t, "min", fieldErrs[1].Rule)
+	assert.Equal(t, "1", fieldErrs[1].Param)
+
+	// Decoding carries on past failed fields.
+	assert.Equal(t, 0, r.Adults)
+	assert.Equal(t, "LBG", r.Outward.Station)
+}
+
+func TestValidationInStructSlices(t *testing.T) {
+	var r struct {
+		Legs []TestValidatedLeg `query:"legs"`
+	}
+
+	values, err := url.ParseQuery("legs[0][station]=TBW&legs[1][station]=X&legs[2][station]=LONDON")
+	assert.Nil(t, err)
+
+	err = mapper.Unmarshal(values, &r)
+	assert.EqualError(t, err, "Field `Legs[1].Station` has 1 characters, expected 3; Field `Legs[2].Station` has 6 characters, expected 3")
+	assert.Equal(t, 3, len(r.Legs))
+}
+
+func TestValidationPatternWithComma(t *testing.T) {
+	var r struct {
+		Code  string `query:"code,pattern=^[A-Z]{2,3}$,required"`
+		Split string `query:"split,pattern=^[a-z]+\\,[a-z]+$"`
+	}
+
+	assert.Nil(t, mapper.Unmarshal(url.Values{"code": {"TBW"}, "split": {"a,b"}}, &r))
+	assert.Equal(t, "TBW", r.Code)
+	assert.Equal(t, "a,b", r.Split)
+
+	err := mapper.Unmarshal(url.Values{"code": {"TBWX"}}, &r)
+	assert.EqualError(t, err, "Provided value `TBWX` for field `Code` does not match `^[A-Z]{2,3}$`")
+}
+
+func TestIncorrectValidationOptions(t *testing.T) {
+	var badMin struct {
+		Adults int `query:"adults,min=one"`
+	}
+	err := mapper.Unmarshal(url.Values{"adults": {"1"}}, &badMin)
+	assert.EqualError(t, err, "Invalid value `one` for option `min` of field `Adults`")
+
+	var badPattern struct {
+		Code string `query:"code,pattern=[A-Z"`
+	}
+	err = mapper.Unmarshal(url.Values{"code": {"A"}}, &badPattern)
+	assert.EqualError(t, err, "Invalid value `[A-Z` for option `pattern` of field `Code`")
+
+	var cutPattern struct {
+		Code string `query:"code,pattern=^[A-Z]+(a,b)$"`
+	}
+	err = mapper.Unmarshal(url.Values{"code": {"A"}}, &cutPattern)
+	assert.IsType(t, &mapper.ConfigError{}, err)
+	assert.Contains(t, err.Error(), "Option `b)$` following the pattern of field `Code` is not an option")
+
+	var badLen struct {
+		Adults int `query:"adults,len=1"`
+	}
+	err = mapper.Unmarshal(url.Values{"adults": {"1"}}, &badLen)
+	assert.EqualError(t, err, "Option `len` is not supported for field `Adults`")
+
+	var badOneOf struct {
+		Adults int `query:"adults,oneof=1|two"`
+	}
+	err = mapper.Unmarshal(url.Values{"adults": {"3"}}, &badOneOf)
+	assert.EqualError(t, err, "Invalid value `1|two` for option `oneof` of field `Adults`")
+}
+
+type TestValidatorsRequest struct {
+	Email   string  `query:"email,validate=email"`
+	Token   string  `query:"token,validate=uuidv4"`
+	Country string  `query:"country,validate=iso3166alpha2|uppercase"`
+	Site    *string `query:"site,validate=url"`
+}
+
+func TestValidators(t *testing.T) {
+	var r = TestValidatorsRequest{}
+
+	values, err := url.ParseQuery("email=jane@example.com&token=a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11&country=GB&site=https://example.com")
+	assert.Nil(t, err)
+
+	err = mapper.Unmarshal(values, &r)
+	assert.Nil(t, err)
+	assert.Equal(t, "GB", r.Country)
+	assert.Equal(t, "https://example.com", *r.Site)
+
+	values, err = url.ParseQuery("email=jane&token=1234&country=gb")
+	assert.Nil(t, err)
+
+	err = mapper.Unmarshal(values, &r)
+	assert.EqualError(t, err, "Provided value `jane` for field `Email` is not a valid email; "+
+		"Provided value `1234` for field `Token` is not a valid uuidv4; "+
+		"Provided value `gb` for field `Country` is not a valid iso3166alpha2; "+
+		"Provided value `gb` for field `Country` is not a valid uppercase")
+
+	fieldErrs := err.(mapper.ValidationErrors)
+	assert.Equal(t, "validate", fieldErrs[3].Rule)
+	assert.Equal(t, "uppercase", fieldErrs[3].Param)
+}
+
+func TestIncorrectValidators(t *testing.T) {
+	var unknown struct {
+		Code string `query:"code,validate=crs2"`
+	}
+	err := mapper.Unmarshal(url.Values{"code": {"TBW"}}, &unknown)
+	assert.EqualError(t, err, "Unknown validator `crs2` for field `Code`")
+
+	var unsupported struct {
+		Adults int `query:"adults,validate=int"`
+	}
+	err = mapper.Unmarshal(url.Values{"adults": {"1"}}, &unsupported)
+	assert.EqualError(t, err, "Option `validate` is not supported for field `Adults`")
+}
+
+type TestCrossFieldRequest struct {
+	OutwardDate  time.Time    `query:"outward,layout=2006-01-02"`
+	ReturnDate   time.Time    `query:"inward,layout=2006-01-02,gtfield=OutwardDate"`
+	Adults       int          `query:"adults"`
+	Children     uint8        `query:"children,ltefield=Adults*4"`
+	Railcard     string       `query:"railcard,required_without=DiscountCode,excluded_with=DiscountCode"`
+	DiscountCode string       `query:"discount_code"`
+	Window       TestTimeSpan `query:"window"`
+}
+
+type TestTimeSpan struct {
+	From int `query:"from"`
+	To   int `query:"to,gtefield=From,required_with=From"`
+}
+
+func TestCrossFieldValidation(t *testing.T) {
+	var r = TestCrossFieldRequest{}
+
+	values, err := url.ParseQuery("outward=2017-01-10&inward=2017-01-12&adults=1&children=4&railcard=YNG&window.from=8&window.to=8")
+	assert.Nil(t, err)
+
+	err = mapper.Unmarshal(values, &r)
+	assert.Nil(t, err)
+
+	// Comparisons are skipped when either key is absent.
+	values, err = url.ParseQuery("inward=2017-01-12&children=2&discount_code=SPRING")
+	assert.Nil(t, err)
+
+	err = mapper.Unmarshal(values, &TestCrossFieldRequest{})
+	assert.Nil(t, err)
+}
+
+func TestCrossFieldValidationFailures(t *testing.T) {
+	failures := map[string]string{
+		"outward=2017-01-10&inward=2017-01-10&railcard=YNG": "Field `ReturnDate` must be greater than `OutwardDate`",
+		"adults=1&children=5&railcard=YNG":                  "Field `Children` must be less than or equal to `Adults*4`",
+		"adults=1":                                          "Field `Railcard` is required when `DiscountCode` is not provided",
+		"railcard=YNG&discount_code=SPRING":                 "Field `Railcard` must not be provided together with `DiscountCode`",
+		"railcard=YNG&window.from=9&window.to=8":            "Field `Window.To` must be greater than or equal to `Window.From`",
+		"railcard=YNG&window.from=9":                        "Field `Window.To` is required when `Window.From` is provided",
+	}
+	for query, expected := range failures {
+		values, err := url.ParseQuery(query)
+		assert.Nil(t, err)
+
+		var r = TestCrossFieldRequest{}
+		err = mapper.Unmarshal(values, &r)
+		assert.EqualError(t, err, expected, query)
+	}
+
+	values, err := url.ParseQuery("adults=1&children=5&railcard=YNG")
+	assert.Nil(t, err)
+
+	err = mapper.Unmarshal(values, &TestCrossFieldRequest{})
+	fieldErrs := err.(mapper.ValidationErrors)
+	assert.Equal(t, "Children", fieldErrs[0].Field)
+	assert.Equal(t, "ltefield", fieldErrs[0].Rule)
+	assert.Equal(t, "Adults*4", fieldErrs[0].Param)
+}
+
+func TestIncorrectCrossFieldOptions(t *testing.T) {
+	var unknown struct {
+		To int `query:"to,gtfield=Form"`
+	}
+	err := mapper.Unmarshal(url.Values{"to": {"1"}}, &unknown)
+	assert.EqualError(t, err, "Unknown field `Form` in option `gtfield` of field `To`")
+
+	var mismatched struct {
+		From time.Time `query:"from,unix"`
+		To   int       `query:"to,gtfield=From"`
+	}
+	err = mapper.Unmarshal(url.Values{"from": {"1"}, "to": {"2"}}, &mismatched)
+	assert.EqualError(t, err, "Option `gtfield` is not supported for field `To`")
+
+	var badFactor struct {
+		From int `query:"from"`
+		To   int `query:"to,ltfield=From*x"`
+	}
+	err = mapper.Unmarshal(url.Values{"from": {"1"}, "to": {"2"}}, &badFactor)
+	assert.EqualError(t, err, "Invalid value `x` for option `ltfield` of field `To`")
+}
+
+var validateCalls []string
+
+type TestHookLeg struct {
+	Station string `query:"station"`
+}
+
+func (l *TestHookLeg) Validate() error {
+	validateCalls = append(validateCalls, "leg "+l.Station)
+	return nil
+}
+
+type TestHookPaging struct {
+	Page int `query:"page"`
+}
+
+func (p TestHookPaging) Validate() error {
+	validateCalls = append(validateCalls, "paging")
+	if p.Page > 10 {
+		return &mapper.FieldError{Field: "Paging.Page", Key: "page", Rule: "max", Param: "10", Message: "Too many pages"}
+	}
+	return nil
+}
+
+type TestHookBase struct {
+	Channel string `query:"channel"`
+}
+
+func (b *TestHookBase) Validate() error {
+	validateCalls = append(validateCalls, "base")
+	if b.Channel == "" {
+		return errors.New("No channel given")
+	}
+	return nil
+}
+
+type TestHookRequest struct {
+	TestHookBase
+	Paging TestHookPaging `query:",inline"`
+	Legs   []TestHookLeg  `query:"legs"`
+	Adults int            `query:"adults,min=1"`
+}
+
+func TestValidateHook(t *testing.T) {
+	var r = TestHookRequest{}
+
+	values, err := url.ParseQuery("channel=web&page=2&legs[0][station]=TBW&legs[1][station]=LBG")
+	assert.Nil(t, err)
+
+	validateCalls = nil
+	err = mapper.Unmarshal(values, &r)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"leg TBW", "leg LBG", "base", "paging"}, validateCalls)
+
+	values, err = url.ParseQuery("page=11&adults=0")
+	assert.Nil(t, err)
+
+	err = mapper.Unmarshal(values, &TestHookRequest{})
+	assert.EqualError(t, err, "Provided value `0` for field `Adults` is less than 1; No channel given; Too many pages")
+
+	fieldErrs := err.(mapper.ValidationErrors)
+	assert.Equal(t, "TestHookBase", fieldErrs[1].Field)
+	assert.Equal(t, "Validate", fieldErrs[1].Rule)
+	assert.Equal(t, "Paging.Page", fieldErrs[2].Field)
+}
+
+type TestHookOverride struct {
+	TestHookBase
+}
+
+func (o TestHookOverride) Validate() error {
+	validateCalls = append(validateCalls, "override")
+	if o.Channel != "web" {
+		return mapper.ValidationErrors{{Field: "Channel", Key: "channel", Rule: "oneof", Param: "web", Message: "Unknown channel"}}
+	}
+	return nil
+}
+
+func TestValidateHookEmbedded(t *testing.T) {
+	// Embedded structs are validated before the struct embedding them.
+	validateCalls = nil
+	err := mapper.Unmarshal(url.Values{"channel": {"app"}}, &TestHookOverride{})
+	assert.EqualError(t, err, "Unknown channel")
+	assert.Equal(t, []string{"base", "override"}, validateCalls)
+
+	validateCalls = nil
+	err = mapper.Unmarshal(url.Values{}, &TestHookOverride{})
+	assert.EqualError(t, err, "No channel given; Unknown channel")
+	assert.Equal(t, []string{"base", "override"}, validateCalls)
+
+	// A struct embedded with a key is validated once, as a nested field,
+	// although its method is promoted.
+	var keyed struct {
+		TestHookBase `query:"base"`
+	}
+
+	validateCalls = nil
+	err = mapper.Unmarshal(url.Values{"base.channel": {"web"}}, &keyed)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"base"}, validateCalls)
+
+	var both struct {
+		TestHookBase
+		TestHookPaging
+	}
+
+	validateCalls = nil
+	err = mapper.Unmarshal(url.Values{"channel": {"web"}, "page": {"1"}}, &both)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"base", "paging"}, validateCalls)
+
+	var nested struct {
+		Leg *TestHookLeg `query:"leg"`
+	}
+
+	validateCalls = nil
+	err = mapper.Unmarshal(url.Values{}, &nested)
+	assert.Nil(t, err)
+	assert.Equal(t, []string(nil), validateCalls)
+}